@@ -0,0 +1,44 @@
+package tracefs
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// Arch is a CPU architecture, named as in GOARCH.
+type Arch string
+
+const (
+	ArchAMD64   Arch = "amd64"
+	ArchARM64   Arch = "arm64"
+	ArchRISCV64 Arch = "riscv64"
+)
+
+// HostArch is the architecture of the running program.
+var HostArch = Arch(runtime.GOARCH)
+
+// argRegisters are the integer argument registers of each architecture's C
+// calling convention, using the kernel's register names.
+var argRegisters = map[Arch][]string{
+	// System V AMD64 ABI.
+	ArchAMD64: {"di", "si", "dx", "cx", "r8", "r9"},
+	// AAPCS64.
+	ArchARM64:   {"x0", "x1", "x2", "x3", "x4", "x5", "x6", "x7"},
+	ArchRISCV64: {"a0", "a1", "a2", "a3", "a4", "a5", "a6", "a7"},
+}
+
+// ArgReg returns a FetchArg for the register holding the idx'th (0 based)
+// integer or pointer argument of a function on arch, e.g. ArgReg(ArchAMD64,
+// 0) fetches %di. It is only valid at function entry, and only for
+// arguments passed in registers; later arguments are on the stack (see
+// FetchStackN).
+func ArgReg(arch Arch, idx int) (FetchArg, error) {
+	regs, ok := argRegisters[arch]
+	if !ok {
+		return nil, fmt.Errorf("%w: unsupported architecture %q", ErrInvalidValue, arch)
+	}
+	if idx < 0 || idx >= len(regs) {
+		return nil, fmt.Errorf("%w: %s passes %d arguments in registers, got index %d", ErrInvalidValue, arch, len(regs), idx)
+	}
+	return FetchRegister(regs[idx]), nil
+}
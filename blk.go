@@ -0,0 +1,137 @@
+package tracefs
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// sysBlockPath is the sysfs directory with a node for every block device
+// and partition.
+var sysBlockPath = "/sys/class/block"
+
+// BlockSystem is the event system with the block layer tracepoints
+// (block_rq_issue, block_rq_complete, etc.).
+const BlockSystem = "block"
+
+// BlkTraceConfig limits what the blk tracer records for a device. The zero
+// value traces every action over the whole device.
+type BlkTraceConfig struct {
+	// ActMask is a comma separated list of actions to record, e.g.
+	// "read,write,issue,complete". Empty records all actions.
+	ActMask string
+	// StartLBA and EndLBA restrict tracing to a sector range. Both 0 means
+	// the whole device.
+	StartLBA uint64
+	EndLBA   uint64
+	// PID restricts tracing to requests from one process. 0 means any.
+	PID int
+}
+
+// blkTraceDir returns the sysfs trace directory for dev, which may be a
+// bare name like "sda" or a /dev path.
+func blkTraceDir(dev string) string {
+	return filepath.Join(sysBlockPath, filepath.Base(dev), "trace")
+}
+
+// EnableBlkTrace turns on blktrace for dev through its sysfs trace
+// directory (/sys/class/block/<dev>/trace). The blk tracer only records
+// devices enabled this way: set it with SetTracer(BlkTracer) on the root
+// instance to see their requests in the trace buffer. The device controls
+// live in sysfs rather than tracefs, so they are global to the system.
+func (i *Instance) EnableBlkTrace(dev string, cfg BlkTraceConfig) error {
+	dir := blkTraceDir(dev)
+
+	// Setting an attribute before enable sets up the trace with that value.
+	attrs := []struct {
+		name  string
+		value string
+		set   bool
+	}{
+		{"act_mask", cfg.ActMask, cfg.ActMask != ""},
+		{"start_lba", strconv.FormatUint(cfg.StartLBA, 10), cfg.StartLBA != 0 || cfg.EndLBA != 0},
+		{"end_lba", strconv.FormatUint(cfg.EndLBA, 10), cfg.StartLBA != 0 || cfg.EndLBA != 0},
+		{"pid", strconv.Itoa(cfg.PID), cfg.PID != 0},
+	}
+	for _, a := range attrs {
+		if !a.set {
+			continue
+		}
+		if err := i.writePath(filepath.Join(dir, a.name), []byte(a.value), false); err != nil {
+			return fmt.Errorf("set blktrace %s for %s: %w", a.name, dev, i.wrapErr(err))
+		}
+	}
+
+	return i.wrapErr(i.writePath(filepath.Join(dir, "enable"), []byte("1"), false))
+}
+
+// DisableBlkTrace turns off blktrace for dev.
+func (i *Instance) DisableBlkTrace(dev string) error {
+	return i.wrapErr(i.writePath(filepath.Join(blkTraceDir(dev), "enable"), []byte("0"), false))
+}
+
+// BlkTraceEnabled reports whether blktrace is enabled for dev.
+func (i *Instance) BlkTraceEnabled(dev string) (bool, error) {
+	data, err := readPath(i.fsys(), filepath.Join(blkTraceDir(dev), "enable"))
+	if err != nil {
+		return false, i.wrapErr(err)
+	}
+	return string(bytes.TrimSpace(data)) == "1", nil
+}
+
+// blockDevNumber returns the kernel dev_t for dev as it appears in the dev
+// field of block events: major << 20 | minor.
+func (i *Instance) blockDevNumber(dev string) (uint64, error) {
+	p := filepath.Join(sysBlockPath, filepath.Base(dev), "dev")
+	data, err := readPath(i.fsys(), p)
+	if err != nil {
+		return 0, i.wrapErr(err)
+	}
+
+	majStr, minStr, ok := strings.Cut(string(bytes.TrimSpace(data)), ":")
+	if !ok {
+		return 0, fmt.Errorf("invalid device number in %s: %s", p, data)
+	}
+	major, err := strconv.ParseUint(majStr, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid device number in %s: %w", p, err)
+	}
+	minor, err := strconv.ParseUint(minStr, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid device number in %s: %w", p, err)
+	}
+	return major<<20 | minor, nil
+}
+
+// EnableBlockEvents enables the block event system. If dev is not empty
+// the events are filtered to that device. Unlike the blk tracer this works
+// in any instance and alongside other events.
+func (i *Instance) EnableBlockEvents(dev string) error {
+	filter := "0"
+	if dev != "" {
+		n, err := i.blockDevNumber(dev)
+		if err != nil {
+			return err
+		}
+		filter = fmt.Sprintf("dev == %d", n)
+	}
+
+	// The system level filter applies to every event in the system that
+	// has a dev field.
+	p := filepath.Join("events", BlockSystem, "filter")
+	if err := i.writeFile(p, []byte(filter)); err != nil {
+		return i.annotateErr(err, filter)
+	}
+	return i.EnableSystem(BlockSystem)
+}
+
+// DisableBlockEvents disables the block event system and clears its
+// filter.
+func (i *Instance) DisableBlockEvents() error {
+	if err := i.DisableSystem(BlockSystem); err != nil {
+		return err
+	}
+	return i.writeFile(filepath.Join("events", BlockSystem, "filter"), []byte("0"))
+}
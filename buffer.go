@@ -0,0 +1,110 @@
+package tracefs
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ErrBufferSizePerCPU is returned by BufferSizeKB when the per-cpu buffers
+// have different sizes.
+var ErrBufferSizePerCPU = errors.New("per-cpu buffer sizes differ")
+
+// BufferSizeKB returns the size of each per-cpu ring buffer in KB.
+func (i *Instance) BufferSizeKB() (int, error) {
+	return i.bufferSizeKB("buffer_size_kb")
+}
+
+// SetBufferSizeKB sets the size of every per-cpu ring buffer to kb.
+func (i *Instance) SetBufferSizeKB(kb int) error {
+	if kb <= 0 {
+		return fmt.Errorf("%w: buffer size must be positive: %d", ErrInvalidValue, kb)
+	}
+	return i.writeInt("buffer_size_kb", kb)
+}
+
+// BufferTotalSizeKB returns the combined size of all per-cpu buffers in KB.
+func (i *Instance) BufferTotalSizeKB() (int, error) {
+	return i.bufferSizeKB("buffer_total_size_kb")
+}
+
+// BufferPercent returns how full, as a percentage, the ring buffer must be
+// before blocked readers are woken.
+func (i *Instance) BufferPercent() (int, error) {
+	return i.readInt("buffer_percent")
+}
+
+// SetBufferPercent sets how full the ring buffer must be before blocked
+// readers are woken. 0 wakes readers on any data.
+func (i *Instance) SetBufferPercent(percent int) error {
+	if percent < 0 || percent > 100 {
+		return fmt.Errorf("%w: buffer percent must be between 0 and 100: %d", ErrInvalidValue, percent)
+	}
+	return i.writeInt("buffer_percent", percent)
+}
+
+// CPUBufferSizeKB returns the ring buffer size in KB for cpu.
+func (i *Instance) CPUBufferSizeKB(cpu int) (int, error) {
+	dir, err := i.cpuDir(cpu)
+	if err != nil {
+		return 0, err
+	}
+	return i.bufferSizeKB(filepath.Join(dir, "buffer_size_kb"))
+}
+
+// SetCPUBufferSizeKB sets the ring buffer size for cpu to kb.
+func (i *Instance) SetCPUBufferSizeKB(cpu, kb int) error {
+	if kb <= 0 {
+		return fmt.Errorf("%w: buffer size must be positive: %d", ErrInvalidValue, kb)
+	}
+	dir, err := i.cpuDir(cpu)
+	if err != nil {
+		return err
+	}
+	return i.writeInt(filepath.Join(dir, "buffer_size_kb"), kb)
+}
+
+// FreeBuffer shrinks the ring buffer to its minimum size, releasing its
+// memory. The kernel frees the buffer when free_buffer is closed, which
+// happens before FreeBuffer returns. Open trace_pipe readers keep working
+// but only see events that fit in the minimal buffer until it is resized
+// with SetBufferSizeKB. If the disable_on_free option is set, tracing is
+// also turned off.
+func (i *Instance) FreeBuffer() error {
+	return i.writeFile("free_buffer", []byte("1"))
+}
+
+// cpuDir returns the per_cpu directory name for cpu, relative to the
+// instance path.
+func (i *Instance) cpuDir(cpu int) (string, error) {
+	dir := filepath.Join("per_cpu", fmt.Sprintf("cpu%d", cpu))
+	_, err := i.fsys().Stat(filepath.Join(i.path, dir))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("cpu %d does not exist or is offline", cpu)
+	} else if err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// bufferSizeKB parses a buffer_size_kb file. Before the buffer is first
+// used the kernel reports "7 (expanded: 1408)"; the current size is returned.
+func (i *Instance) bufferSizeKB(name string) (int, error) {
+	data, err := i.readFile(name)
+	if err != nil {
+		return 0, err
+	}
+
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return 0, fmt.Errorf("empty %s", name)
+	}
+	if fields[0] == "X" {
+		return 0, ErrBufferSizePerCPU
+	}
+
+	return strconv.Atoi(fields[0])
+}
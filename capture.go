@@ -0,0 +1,108 @@
+package tracefs
+
+import (
+	"context"
+	"errors"
+)
+
+// CaptureConfig describes the tracing setup for Capture. Zero fields leave
+// the corresponding setting unchanged.
+type CaptureConfig struct {
+	// Tracer is set as the current tracer for the capture.
+	Tracer Tracer
+	// Events are enabled for the capture.
+	Events []Event
+	// Filters are event filter expressions, keyed by event. The events do
+	// not have to be in Events.
+	Filters map[Event]string
+	// BufferSizeKB is the per-cpu ring buffer size.
+	BufferSizeKB int
+}
+
+// Capture applies cfg, clears the trace buffer, enables tracing and
+// collects events from trace_pipe until ctx is done. The previous tracer,
+// tracing_on state, buffer size, event enables and filters are restored
+// before returning, even on error. The events read before ctx was done are
+// returned; ctx ending the capture is not an error.
+//
+// Capture only uses the exported Instance methods, so callers needing a
+// different workflow can combine them directly.
+func (i *Instance) Capture(ctx context.Context, cfg CaptureConfig) (events []TraceEvent, err error) {
+	var restore []func() error
+	defer func() {
+		// Undo in reverse order of setup.
+		for n := len(restore) - 1; n >= 0; n-- {
+			if restoreErr := restore[n](); err == nil {
+				err = restoreErr
+			}
+		}
+	}()
+
+	if cfg.BufferSizeKB > 0 {
+		prev, err := i.BufferSizeKB()
+		if err != nil {
+			return nil, err
+		}
+		if err := i.SetBufferSizeKB(cfg.BufferSizeKB); err != nil {
+			return nil, err
+		}
+		restore = append(restore, func() error { return i.SetBufferSizeKB(prev) })
+	}
+
+	for e, expr := range cfg.Filters {
+		e := e
+		prev, err := i.EventFilter(e)
+		if err != nil {
+			return nil, err
+		}
+		if err := i.SetEventFilter(e, expr); err != nil {
+			return nil, err
+		}
+		restore = append(restore, func() error {
+			if prev == "" {
+				return i.ClearEventFilter(e)
+			}
+			return i.SetEventFilter(e, prev)
+		})
+	}
+
+	for _, e := range cfg.Events {
+		e := e
+		on, err := i.EventEnabled(e)
+		if err != nil {
+			return nil, err
+		}
+		if on {
+			continue
+		}
+		if err := i.EnableEvent(e); err != nil {
+			return nil, err
+		}
+		restore = append(restore, func() error { return i.DisableEvent(e) })
+	}
+
+	run := func() error {
+		if err := i.ClearTrace(); err != nil {
+			return err
+		}
+		return i.WhileTracing(func() error {
+			err := i.StreamTrace(ctx, func(line string) error {
+				if ev, err := ParseTraceLine(line); err == nil {
+					events = append(events, ev)
+				}
+				return nil
+			})
+			if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+				return nil
+			}
+			return err
+		})
+	}
+
+	if cfg.Tracer != "" {
+		err = i.WithTracer(cfg.Tracer, run)
+	} else {
+		err = run()
+	}
+	return events, err
+}
@@ -0,0 +1,86 @@
+package tracefs
+
+import (
+	"context"
+	"reflect"
+	"syscall"
+	"testing"
+)
+
+func newCaptureFS() *memFS {
+	fsys := newTracefs("/t", map[string]string{
+		"/t/buffer_size_kb":                   "1408\n",
+		"/t/trace":                            "",
+		"/t/trace_pipe":                       "          <idle>-0       [001] d..2.  2383.125093: sched_switch: prev_comm=swapper/1 prev_pid=0 prev_prio=120 prev_state=R ==> next_comm=bash next_pid=1234 next_prio=120\n",
+		"/t/events/sched/sched_switch/enable": "0\n",
+		"/t/events/sched/sched_switch/filter": "none\n",
+	})
+	fsys.addFile("/t/tracing_on", "0\n")
+	return fsys
+}
+
+var captureSwitch = Event{System: "sched", Name: "sched_switch"}
+
+func TestCaptureRestoresInReverse(t *testing.T) {
+	fsys := newCaptureFS()
+	i := RootInstance("/t", WithFS(fsys))
+
+	events, err := i.Capture(context.Background(), CaptureConfig{
+		Tracer:       FunctionTracer,
+		Events:       []Event{captureSwitch},
+		Filters:      map[Event]string{captureSwitch: "next_pid == 1234"},
+		BufferSizeKB: 4096,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].Function != "sched_switch" {
+		t.Errorf("Capture events = %+v, want the sched_switch from trace_pipe", events)
+	}
+
+	want := []memWrite{
+		{"/t/buffer_size_kb", "4096"},
+		{"/t/events/sched/sched_switch/filter", "next_pid == 1234"},
+		{"/t/events/sched/sched_switch/enable", "1"},
+		{"/t/current_tracer", "function"},
+		{"/t/trace", ""},
+		{"/t/tracing_on", "1"},
+		// Restored in reverse order of setup: tracing_on by WhileTracing,
+		// the tracer and tracing_on by WithTracer, then the event, its
+		// filter and the buffer size.
+		{"/t/tracing_on", "0"},
+		{"/t/current_tracer", "nop"},
+		{"/t/tracing_on", "0"},
+		{"/t/events/sched/sched_switch/enable", "0"},
+		{"/t/events/sched/sched_switch/filter", "0"},
+		{"/t/buffer_size_kb", "1408"},
+	}
+	if !reflect.DeepEqual(fsys.writes, want) {
+		t.Errorf("Capture writes:\n got %q\nwant %q", fsys.writes, want)
+	}
+}
+
+func TestCaptureRestoresOnSetupError(t *testing.T) {
+	fsys := newCaptureFS()
+	fsys.writeErrs["/t/events/sched/sched_switch/enable"] = []error{syscall.EINVAL}
+	i := RootInstance("/t", WithFS(fsys))
+
+	_, err := i.Capture(context.Background(), CaptureConfig{
+		Events:       []Event{captureSwitch},
+		Filters:      map[Event]string{captureSwitch: "next_pid == 1234"},
+		BufferSizeKB: 4096,
+	})
+	if err == nil {
+		t.Fatal("Capture succeeded with a failing event enable")
+	}
+
+	want := []memWrite{
+		{"/t/buffer_size_kb", "4096"},
+		{"/t/events/sched/sched_switch/filter", "next_pid == 1234"},
+		{"/t/events/sched/sched_switch/filter", "0"},
+		{"/t/buffer_size_kb", "1408"},
+	}
+	if !reflect.DeepEqual(fsys.writes, want) {
+		t.Errorf("Capture writes:\n got %q\nwant %q", fsys.writes, want)
+	}
+}
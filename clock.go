@@ -0,0 +1,52 @@
+package tracefs
+
+import (
+	"fmt"
+	"strings"
+)
+
+var traceClockPath = "trace_clock"
+
+// parseTraceClock splits the trace_clock file into the available clocks and
+// the selected one, which the kernel marks with brackets (e.g. "[local]").
+func parseTraceClock(data []byte) (clocks []string, current string) {
+	for _, f := range strings.Fields(string(data)) {
+		if strings.HasPrefix(f, "[") && strings.HasSuffix(f, "]") {
+			f = f[1 : len(f)-1]
+			current = f
+		}
+		clocks = append(clocks, f)
+	}
+	return clocks, current
+}
+
+// TraceClock returns the clock used for trace timestamps.
+func (i *Instance) TraceClock() (string, error) {
+	data, err := i.readFile(traceClockPath)
+	if err != nil {
+		return "", err
+	}
+
+	_, current := parseTraceClock(data)
+	if current == "" {
+		return "", fmt.Errorf("no clock selected in %s: %s", traceClockPath, data)
+	}
+	return current, nil
+}
+
+// SetTraceClock sets the clock used for trace timestamps, e.g. "mono" or
+// "global".
+func (i *Instance) SetTraceClock(clock string) error {
+	return i.writeFile(traceClockPath, []byte(clock))
+}
+
+// AvailableClocks returns the clocks supported by the kernel.
+func (i *Instance) AvailableClocks() ([]string, error) {
+	data, err := i.readFile(traceClockPath)
+	if err != nil {
+		return nil, err
+	}
+
+	clocks, _ := parseTraceClock(data)
+	return clocks, nil
+}
@@ -0,0 +1,52 @@
+package tracefs
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseTraceClock(t *testing.T) {
+	tests := []struct {
+		data    string
+		clocks  []string
+		current string
+	}{
+		{
+			data:    "[local] global counter uptime perf mono mono_raw boot tai x86-tsc\n",
+			clocks:  []string{"local", "global", "counter", "uptime", "perf", "mono", "mono_raw", "boot", "tai", "x86-tsc"},
+			current: "local",
+		},
+		{
+			data:    "local global counter uptime perf [mono] mono_raw boot",
+			clocks:  []string{"local", "global", "counter", "uptime", "perf", "mono", "mono_raw", "boot"},
+			current: "mono",
+		},
+		{
+			data:   "local global",
+			clocks: []string{"local", "global"},
+		},
+	}
+
+	for _, tt := range tests {
+		clocks, current := parseTraceClock([]byte(tt.data))
+		if !reflect.DeepEqual(clocks, tt.clocks) || current != tt.current {
+			t.Errorf("parseTraceClock(%q) = %q, %q, want %q, %q", tt.data, clocks, current, tt.clocks, tt.current)
+		}
+	}
+}
+
+func TestTraceClock(t *testing.T) {
+	fsys := newTracefs("/t", map[string]string{
+		"/t/trace_clock": "local [global] counter uptime perf mono mono_raw boot\n",
+	})
+	i := RootInstance("/t", WithFS(fsys))
+
+	if clock, err := i.TraceClock(); err != nil || clock != "global" {
+		t.Errorf("TraceClock() = %q, %v, want global", clock, err)
+	}
+
+	fsys.addFile("/t/trace_clock", "local global\n")
+	if _, err := i.TraceClock(); err == nil {
+		t.Error("TraceClock() without a selected clock succeeded")
+	}
+}
@@ -0,0 +1,47 @@
+package tracefs
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	savedCmdlinesPath     = "saved_cmdlines"
+	savedCmdlinesSizePath = "saved_cmdlines_size"
+)
+
+// SavedCmdlines returns the kernel's cached pid to comm mapping used to
+// annotate trace output.
+func (i *Instance) SavedCmdlines() (map[int]string, error) {
+	lines, err := i.top().readLines(savedCmdlinesPath)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(map[int]string, len(lines))
+	for _, line := range lines {
+		// The comm may itself contain spaces.
+		pidStr, comm, _ := strings.Cut(line, " ")
+		pid, err := strconv.Atoi(pidStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid saved_cmdlines line %q: %w", line, err)
+		}
+		out[pid] = comm
+	}
+	return out, nil
+}
+
+// SavedCmdlinesSize returns the number of entries in the saved comm cache.
+func (i *Instance) SavedCmdlinesSize() (int, error) {
+	return i.readInt(savedCmdlinesSizePath)
+}
+
+// SetSavedCmdlinesSize sets the number of entries in the saved comm cache.
+// If the cache is too small, traces show "<...>" for some comms.
+func (i *Instance) SetSavedCmdlinesSize(n int) error {
+	if n <= 0 {
+		return fmt.Errorf("%w: saved cmdlines size must be positive: %d", ErrInvalidValue, n)
+	}
+	return i.writeInt(savedCmdlinesSizePath, n)
+}
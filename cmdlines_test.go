@@ -0,0 +1,34 @@
+package tracefs
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSavedCmdlines(t *testing.T) {
+	fsys, _, child := newTestChild(t)
+	fsys.addFile("/t/saved_cmdlines", "1 systemd\n1234 bash\n4321 Web Content\n2817 kworker/u16:3\n")
+
+	// saved_cmdlines is global and only exists in the root.
+	got, err := child.SavedCmdlines()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[int]string{
+		1:    "systemd",
+		1234: "bash",
+		4321: "Web Content",
+		2817: "kworker/u16:3",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("SavedCmdlines = %v, want %v", got, want)
+	}
+}
+
+func TestSavedCmdlinesInvalid(t *testing.T) {
+	fsys := newTracefs("/t", map[string]string{"/t/saved_cmdlines": "bash\n"})
+	i := RootInstance("/t", WithFS(fsys))
+	if _, err := i.SavedCmdlines(); err == nil {
+		t.Error("SavedCmdlines accepted a line without a pid")
+	}
+}
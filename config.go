@@ -0,0 +1,46 @@
+package tracefs
+
+import "os"
+
+// config holds the settings applied by Options. Child instances inherit the
+// config of the instance that created them.
+type config struct {
+	fs        FS
+	validate  bool
+	writeMode os.FileMode
+}
+
+// Option configures a root instance.
+type Option func(*config)
+
+// WithFS sets the filesystem backend. The default is OSFS.
+func WithFS(fsys FS) Option {
+	return func(c *config) {
+		c.fs = fsys
+	}
+}
+
+// WithValidation makes SetTracer check the tracer against
+// available_tracers before writing it, instead of only consulting it after
+// the kernel rejects the write.
+func WithValidation(validate bool) Option {
+	return func(c *config) {
+		c.validate = validate
+	}
+}
+
+// WithWriteMode sets the permission bits passed when opening files for
+// writing. The default is 0644. Tracefs ignores the mode for existing
+// files; it matters mostly for FS backends used in tests.
+func WithWriteMode(mode os.FileMode) Option {
+	return func(c *config) {
+		c.writeMode = mode
+	}
+}
+
+func (i *Instance) writeMode() os.FileMode {
+	if i.cfg.writeMode == 0 {
+		return 0644
+	}
+	return i.cfg.writeMode
+}
@@ -0,0 +1,75 @@
+package tracefs
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var cpuMaskPath = "tracing_cpumask"
+
+// CPUMask returns the CPUs that are traced, parsed from tracing_cpumask.
+func (i *Instance) CPUMask() ([]int, error) {
+	data, err := i.readFile(cpuMaskPath)
+	if err != nil {
+		return nil, err
+	}
+	return parseCPUMask(string(data))
+}
+
+// SetCPUMask restricts tracing to cpus.
+func (i *Instance) SetCPUMask(cpus []int) error {
+	mask, err := formatCPUMask(cpus)
+	if err != nil {
+		return err
+	}
+	return i.writeFile(cpuMaskPath, []byte(mask))
+}
+
+// parseCPUMask parses a kernel cpumask: comma separated 32 bit hex words,
+// most significant word first.
+func parseCPUMask(s string) ([]int, error) {
+	words := strings.Split(strings.TrimSpace(s), ",")
+	cpus := []int{}
+	for n := range words {
+		word := words[len(words)-1-n]
+		if word == "" {
+			continue
+		}
+		v, err := strconv.ParseUint(word, 16, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cpumask %q: %w", s, err)
+		}
+		for bit := 0; bit < 32; bit++ {
+			if v&(1<<bit) != 0 {
+				cpus = append(cpus, n*32+bit)
+			}
+		}
+	}
+	return cpus, nil
+}
+
+// formatCPUMask is the inverse of parseCPUMask.
+func formatCPUMask(cpus []int) (string, error) {
+	if len(cpus) == 0 {
+		return "0", nil
+	}
+
+	sorted := append([]int(nil), cpus...)
+	sort.Ints(sorted)
+	if sorted[0] < 0 {
+		return "", fmt.Errorf("%w: invalid cpu %d", ErrInvalidValue, sorted[0])
+	}
+
+	words := make([]uint32, sorted[len(sorted)-1]/32+1)
+	for _, cpu := range sorted {
+		words[cpu/32] |= 1 << (cpu % 32)
+	}
+
+	parts := make([]string, len(words))
+	for n, w := range words {
+		parts[len(words)-1-n] = fmt.Sprintf("%08x", w)
+	}
+	return strings.Join(parts, ","), nil
+}
@@ -0,0 +1,63 @@
+package tracefs
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestCPUMask(t *testing.T) {
+	tests := []struct {
+		mask string
+		cpus []int
+	}{
+		{"0", []int{}},
+		{"00000001", []int{0}},
+		{"0000000f", []int{0, 1, 2, 3}},
+		{"80000000", []int{31}},
+		{"00000001,00000000", []int{32}},
+		{"00000003,80000001", []int{0, 31, 32, 33}},
+	}
+
+	for _, tt := range tests {
+		got, err := parseCPUMask(tt.mask + "\n")
+		if err != nil {
+			t.Errorf("parseCPUMask(%q): %v", tt.mask, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.cpus) {
+			t.Errorf("parseCPUMask(%q) = %v, want %v", tt.mask, got, tt.cpus)
+		}
+
+		if len(tt.cpus) == 0 {
+			continue
+		}
+		mask, err := formatCPUMask(tt.cpus)
+		if err != nil {
+			t.Errorf("formatCPUMask(%v): %v", tt.cpus, err)
+			continue
+		}
+		if mask != tt.mask {
+			t.Errorf("formatCPUMask(%v) = %q, want %q", tt.cpus, mask, tt.mask)
+		}
+	}
+}
+
+func TestFormatCPUMask(t *testing.T) {
+	if mask, err := formatCPUMask(nil); err != nil || mask != "0" {
+		t.Errorf("formatCPUMask(nil) = %q, %v, want \"0\"", mask, err)
+	}
+	// Input order and duplicates do not matter.
+	if mask, err := formatCPUMask([]int{3, 1, 3}); err != nil || mask != "0000000a" {
+		t.Errorf("formatCPUMask([3 1 3]) = %q, %v, want \"0000000a\"", mask, err)
+	}
+	if _, err := formatCPUMask([]int{-1}); !errors.Is(err, ErrInvalidValue) {
+		t.Errorf("formatCPUMask([-1]) = %v, want ErrInvalidValue", err)
+	}
+}
+
+func TestParseCPUMaskInvalid(t *testing.T) {
+	if _, err := parseCPUMask("zz"); err == nil {
+		t.Error(`parseCPUMask("zz") succeeded`)
+	}
+}
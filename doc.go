@@ -0,0 +1,28 @@
+// Package tracefs controls the Linux kernel tracing filesystem
+// (/sys/kernel/tracing).
+//
+// An Instance is either the root tracing directory or a child instance
+// under instances/. Child instances have the same layout for their own
+// trace buffer and settings, so the per-instance methods (SetTracer,
+// Enable, Disable, the buffer, option, clock, event, filter, trigger, pid
+// and marker methods, and reading the trace) operate on the child's files.
+// A child only lists the tracers that support instances in
+// available_tracers.
+//
+// Some operations are root only:
+//
+//   - Creating, listing and destroying child instances (NewInstance,
+//     ChildInstances, Instance). Destroy itself is called on the child.
+//   - Probe, dynamic and synthetic event definitions. These are global, so
+//     on a child the definition methods (AddUprobeEvent, AddKprobeEvent,
+//     AddDynamicEvent, AddSyntheticEvent and their list and remove
+//     counterparts) use the root's files. The resulting events can then be
+//     enabled in any instance.
+//   - Read-only global tables: available_filter_functions, printk_formats,
+//     saved_cmdlines and kprobe_profile are also read from the root.
+//   - Global tunables such as tracing_thresh, saved_cmdlines_size, the
+//     stack tracer, the function profiler, set_graph_function,
+//     max_graph_depth and hwlat_detector. These are not redirected, since
+//     changing them from a child would affect every instance; on a child
+//     they fail with an error matching ErrNotRoot.
+package tracefs
@@ -0,0 +1,44 @@
+package tracefs
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+var dynamicEventsPath = "dynamic_events"
+
+// DynamicEvents returns the probe and synthetic event definitions listed in
+// dynamic_events. Dynamic events are global, so on a child instance this
+// uses the root's dynamic_events.
+func (i *Instance) DynamicEvents() ([]string, error) {
+	return i.top().readLines(dynamicEventsPath)
+}
+
+// AddDynamicEvent writes rule to dynamic_events. Rules use the same syntax
+// as kprobe_events and uprobe_events, plus "s:" for synthetic events.
+func (i *Instance) AddDynamicEvent(rule string) error {
+	return i.top().appendLine(dynamicEventsPath, rule)
+}
+
+// RemoveDynamicEvent deletes the dynamic event with the given [group/]event
+// name.
+func (i *Instance) RemoveDynamicEvent(name string) error {
+	return i.top().appendLine(dynamicEventsPath, "-:"+name)
+}
+
+// hasDynamicEvents reports whether the kernel provides dynamic_events
+// (Linux 5.0+).
+func (i *Instance) hasDynamicEvents() bool {
+	_, err := i.fsys().Stat(filepath.Join(i.path, dynamicEventsPath))
+	return !errors.Is(err, os.ErrNotExist)
+}
+
+// probeEventsFile returns the file to write probe definitions to:
+// dynamic_events when available, and legacy otherwise.
+func (i *Instance) probeEventsFile(legacy string) string {
+	if i.hasDynamicEvents() {
+		return dynamicEventsPath
+	}
+	return legacy
+}
@@ -0,0 +1,67 @@
+package tracefs
+
+import (
+	"debug/elf"
+	"errors"
+	"fmt"
+)
+
+// ResolveSymbolOffset returns the file offset of symbol in the ELF binary at
+// path, suitable for UprobeEvent.Offset. Both the static and dynamic symbol
+// tables are searched.
+//
+// The symbol's virtual address is translated through the PT_LOAD segment
+// that contains it, which gives the right answer for both PIE and non-PIE
+// binaries.
+func ResolveSymbolOffset(path, symbol string) (uint64, error) {
+	return resolveSymbol(path, symbol, false)
+}
+
+// ResolveFunctionOffset is like ResolveSymbolOffset but only matches
+// function symbols, returning the offset of the function's entry point.
+func ResolveFunctionOffset(path, symbol string) (uint64, error) {
+	return resolveSymbol(path, symbol, true)
+}
+
+func resolveSymbol(path, symbol string, funcOnly bool) (uint64, error) {
+	f, err := elf.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	sym, err := findSymbol(f, symbol, funcOnly)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", path, err)
+	}
+
+	for _, prog := range f.Progs {
+		if prog.Type != elf.PT_LOAD || (funcOnly && prog.Flags&elf.PF_X == 0) {
+			continue
+		}
+		if sym.Value >= prog.Vaddr && sym.Value < prog.Vaddr+prog.Memsz {
+			return sym.Value - prog.Vaddr + prog.Off, nil
+		}
+	}
+
+	return 0, fmt.Errorf("%s: symbol %s at 0x%x is not in a loadable segment", path, symbol, sym.Value)
+}
+
+func findSymbol(f *elf.File, name string, funcOnly bool) (elf.Symbol, error) {
+	for _, load := range []func() ([]elf.Symbol, error){f.Symbols, f.DynamicSymbols} {
+		syms, err := load()
+		if err != nil && !errors.Is(err, elf.ErrNoSymbols) {
+			return elf.Symbol{}, err
+		}
+		for _, s := range syms {
+			if s.Name != name || s.Value == 0 {
+				continue
+			}
+			if funcOnly && elf.ST_TYPE(s.Info) != elf.STT_FUNC {
+				continue
+			}
+			return s, nil
+		}
+	}
+	return elf.Symbol{}, fmt.Errorf("symbol %s not found", name)
+}
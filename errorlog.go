@@ -0,0 +1,125 @@
+package tracefs
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"syscall"
+)
+
+var errorLogPath = "error_log"
+
+// ErrorLogEntry is an entry from error_log, which the kernel writes when it
+// rejects a probe, filter, or trigger.
+type ErrorLogEntry struct {
+	// Timestamp is the kernel timestamp, e.g. "  123.456789".
+	Timestamp string
+	// Location is the subsystem that logged the error, e.g. "trace_kprobe".
+	Location string
+	Message  string
+	Command  string
+	// Pos is the offset into Command of the offending token, or -1 if the
+	// entry has no position.
+	Pos int
+}
+
+// String formats the entry with the command and a caret pointing at the
+// error position.
+func (e ErrorLogEntry) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: %s", e.Location, e.Message)
+	if e.Command != "" {
+		fmt.Fprintf(&b, "\n  Command: %s", e.Command)
+		if e.Pos >= 0 {
+			fmt.Fprintf(&b, "\n           %s^", strings.Repeat(" ", e.Pos))
+		}
+	}
+	return b.String()
+}
+
+// ErrorLog returns the entries in error_log, oldest first.
+func (i *Instance) ErrorLog() ([]ErrorLogEntry, error) {
+	data, err := i.readFile(errorLogPath)
+	if err != nil {
+		return nil, err
+	}
+	return parseErrorLog(string(data)), nil
+}
+
+// ClearErrorLog empties error_log.
+func (i *Instance) ClearErrorLog() error {
+	return i.replaceFile(errorLogPath, nil)
+}
+
+// parseErrorLog parses entries of the form:
+//
+//	[  123.456789] trace_kprobe: error: Invalid fetch argument
+//	  Command: p:myprobe do_sys_open dfd=%ax
+//	                                     ^
+func parseErrorLog(s string) []ErrorLogEntry {
+	var (
+		out      []ErrorLogEntry
+		cmdStart int
+	)
+	for _, line := range strings.Split(s, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmed, "["):
+			end := strings.Index(trimmed, "]")
+			if end < 0 {
+				continue
+			}
+			e := ErrorLogEntry{
+				Timestamp: trimmed[1:end],
+				Pos:       -1,
+			}
+			rest := strings.TrimSpace(trimmed[end+1:])
+			loc, msg, _ := strings.Cut(rest, ": ")
+			e.Location = loc
+			e.Message = strings.TrimPrefix(msg, "error: ")
+			out = append(out, e)
+		case strings.HasPrefix(trimmed, "Command:") && len(out) > 0:
+			idx := strings.Index(line, "Command:") + len("Command:")
+			cmdStart = idx + 1
+			out[len(out)-1].Command = strings.TrimSpace(line[idx:])
+		case trimmed == "^" && len(out) > 0:
+			out[len(out)-1].Pos = strings.Index(line, "^") - cmdStart
+		}
+	}
+	return out
+}
+
+// ErrorLogError is returned when the kernel rejects a write and error_log
+// has an entry explaining why.
+type ErrorLogError struct {
+	Err   error
+	Entry ErrorLogEntry
+}
+
+func (e *ErrorLogError) Error() string {
+	return fmt.Sprintf("%v: %s", e.Err, e.Entry)
+}
+
+func (e *ErrorLogError) Unwrap() error {
+	return e.Err
+}
+
+// annotateErr adds the most recent error_log entry for cmd to err. If
+// error_log is unavailable or has no matching entry, err is returned as is.
+func (i *Instance) annotateErr(err error, cmd string) error {
+	if err == nil || !errors.Is(err, syscall.EINVAL) {
+		return err
+	}
+
+	entries, logErr := i.ErrorLog()
+	if logErr != nil {
+		return err
+	}
+	cmd = strings.TrimSpace(cmd)
+	for n := len(entries) - 1; n >= 0; n-- {
+		if entries[n].Command == cmd {
+			return &ErrorLogError{Err: err, Entry: entries[n]}
+		}
+	}
+	return err
+}
@@ -0,0 +1,46 @@
+package tracefs
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseErrorLog(t *testing.T) {
+	const input = `[  123.456789] trace_kprobe: error: Invalid fetch argument
+  Command: p:myprobe do_sys_open dfd=%ax
+                                     ^
+[  124.000001] hist:sched:sched_switch: error: Couldn't find field
+  Command: hist:keys=bogus
+`
+
+	want := []ErrorLogEntry{
+		{
+			Timestamp: "  123.456789",
+			Location:  "trace_kprobe",
+			Message:   "Invalid fetch argument",
+			Command:   "p:myprobe do_sys_open dfd=%ax",
+			Pos:       26,
+		},
+		{
+			Timestamp: "  124.000001",
+			Location:  "hist:sched:sched_switch",
+			Message:   "Couldn't find field",
+			Command:   "hist:keys=bogus",
+			Pos:       -1,
+		},
+	}
+
+	got := parseErrorLog(input)
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("parseErrorLog:\n got %+v\nwant %+v", got, want)
+	}
+	if got[0].Command[got[0].Pos:] != "%ax" {
+		t.Errorf("Pos %d points at %q, want the bad register", got[0].Pos, got[0].Command[got[0].Pos:])
+	}
+}
+
+func TestParseErrorLogEmpty(t *testing.T) {
+	if got := parseErrorLog(""); len(got) != 0 {
+		t.Errorf("parseErrorLog(\"\") = %+v, want no entries", got)
+	}
+}
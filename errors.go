@@ -0,0 +1,98 @@
+package tracefs
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+var (
+	// ErrNotMounted is returned when tracefs is not mounted at the root
+	// instance path.
+	ErrNotMounted = errors.New("tracefs is not mounted")
+	// ErrNotRoot is returned by operations that must be called on the root
+	// instance, including reads and writes of files that only exist in the
+	// root instance.
+	ErrNotRoot = errors.New("operation requires the root instance")
+	// ErrRootInstance is returned by operations that cannot be performed on
+	// the root instance, such as Destroy.
+	ErrRootInstance = errors.New("operation not permitted on the root instance")
+	// ErrProbeBusy is returned when a probe cannot be removed because it is
+	// enabled or in use.
+	ErrProbeBusy = errors.New("probe is busy")
+	// ErrInstanceBusy is returned by Destroy when the kernel refuses to
+	// remove an instance that is in use.
+	ErrInstanceBusy = errors.New("instance is busy")
+	// ErrInvalidValue is returned when a value is rejected, either before
+	// writing it or by the kernel (EINVAL).
+	ErrInvalidValue = errors.New("invalid value")
+)
+
+// kindError attaches a sentinel error to an underlying error so that
+// errors.Is matches both.
+type kindError struct {
+	kind error
+	err  error
+}
+
+func (e *kindError) Error() string {
+	return e.err.Error()
+}
+
+func (e *kindError) Unwrap() error {
+	return e.err
+}
+
+func (e *kindError) Is(target error) bool {
+	return target == e.kind
+}
+
+func wrapKind(kind, err error) error {
+	return &kindError{kind: kind, err: err}
+}
+
+// rootOnly reports whether err is for a file missing from child instance i
+// that exists in the root instance, such as tracing_thresh. It returns the
+// file's path relative to the instance.
+func (i *Instance) rootOnly(err error) (string, bool) {
+	var pe *os.PathError
+	if !errors.As(err, &pe) {
+		return "", false
+	}
+	rel, relErr := filepath.Rel(i.path, pe.Path)
+	if relErr != nil || strings.HasPrefix(rel, "..") {
+		return "", false
+	}
+	// A destroyed child is missing every file.
+	if _, statErr := i.fsys().Stat(i.path); statErr != nil {
+		return "", false
+	}
+	root := i.Root()
+	_, statErr := i.fsys().Stat(filepath.Join(root.path, rel))
+	return rel, statErr == nil
+}
+
+// wrapErr maps errors from file operations on i to the package's sentinel
+// errors. Permission errors already match os.ErrPermission.
+func (i *Instance) wrapErr(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, os.ErrNotExist):
+		if i.isRoot {
+			// The mountpoint directory exists even when nothing is mounted
+			// on it, so check for a file every tracefs has.
+			if _, statErr := i.fsys().Stat(filepath.Join(i.path, tracingOnPath)); statErr != nil {
+				return wrapKind(ErrNotMounted, err)
+			}
+		} else if rel, ok := i.rootOnly(err); ok {
+			return wrapKind(ErrNotRoot, fmt.Errorf("%s only exists in the root instance: %w", rel, err))
+		}
+	case errors.Is(err, syscall.EINVAL):
+		return wrapKind(ErrInvalidValue, err)
+	}
+	return err
+}
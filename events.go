@@ -0,0 +1,233 @@
+package tracefs
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Event identifies a trace event. An Event with an empty Name refers to
+// every event in System and an empty Event refers to all events.
+type Event struct {
+	System string
+	Name   string
+}
+
+// String returns the event in system:name form.
+func (e Event) String() string {
+	return e.System + ":" + e.Name
+}
+
+// eventDir returns the events directory for e, relative to the instance
+// path.
+func eventDir(e Event) string {
+	if e.System == "" {
+		return "events"
+	} else if e.Name == "" {
+		return filepath.Join("events", e.System)
+	}
+	return filepath.Join("events", e.System, e.Name)
+}
+
+// EnableEvent enables e.
+func (i *Instance) EnableEvent(e Event) error {
+	return i.writeFile(filepath.Join(eventDir(e), "enable"), []byte("1"))
+}
+
+// DisableEvent disables e.
+func (i *Instance) DisableEvent(e Event) error {
+	return i.writeFile(filepath.Join(eventDir(e), "enable"), []byte("0"))
+}
+
+// EventEnabled reports whether e is enabled. For a system or all events, an
+// error is returned if only some of the events are enabled.
+func (i *Instance) EventEnabled(e Event) (bool, error) {
+	result, err := i.readFile(filepath.Join(eventDir(e), "enable"))
+	if err != nil {
+		return false, err
+	}
+	switch string(result) {
+	case "0":
+		return false, nil
+	case "1":
+		return true, nil
+	case "X":
+		return false, fmt.Errorf("events under %s are partially enabled", eventDir(e))
+	}
+
+	return false, fmt.Errorf("unknown enable value: %s", result)
+}
+
+// EnableState is the state of an events enable file.
+type EnableState int
+
+const (
+	Disabled EnableState = iota
+	Enabled
+	// Mixed means some, but not all, of the events are enabled.
+	Mixed
+)
+
+func (s EnableState) String() string {
+	switch s {
+	case Disabled:
+		return "0"
+	case Enabled:
+		return "1"
+	case Mixed:
+		return "X"
+	}
+	return fmt.Sprintf("EnableState(%d)", int(s))
+}
+
+// EnableSystem enables every event in system, e.g. "block".
+func (i *Instance) EnableSystem(system string) error {
+	return i.EnableEvent(Event{System: system})
+}
+
+// DisableSystem disables every event in system.
+func (i *Instance) DisableSystem(system string) error {
+	return i.DisableEvent(Event{System: system})
+}
+
+// SystemEnabled reports whether the events in system are all enabled, all
+// disabled, or mixed.
+func (i *Instance) SystemEnabled(system string) (EnableState, error) {
+	result, err := i.readFile(filepath.Join(eventDir(Event{System: system}), "enable"))
+	if err != nil {
+		return Disabled, err
+	}
+	switch string(result) {
+	case "0":
+		return Disabled, nil
+	case "1":
+		return Enabled, nil
+	case "X":
+		return Mixed, nil
+	}
+
+	return Disabled, fmt.Errorf("unknown enable value: %s", result)
+}
+
+var setEventPath = "set_event"
+
+// SetEvents replaces the enabled events with events. Each entry is of the
+// form system:event, where either part may use shell style wildcards
+// (e.g. "block:*" or "*:sched_switch"). A bare name matches that event in
+// any system. An empty list disables all events.
+func (i *Instance) SetEvents(events []string) error {
+	return i.replaceFile(setEventPath, []byte(strings.Join(events, "\n")))
+}
+
+// AddEvent enables event, using the same syntax as SetEvents.
+func (i *Instance) AddEvent(event string) error {
+	return i.appendLine(setEventPath, event)
+}
+
+// RemoveEvent disables event, using the same syntax as SetEvents.
+func (i *Instance) RemoveEvent(event string) error {
+	return i.appendLine(setEventPath, "!"+event)
+}
+
+// ActiveEvents returns the enabled events from set_event.
+func (i *Instance) ActiveEvents() ([]string, error) {
+	return i.readLines(setEventPath)
+}
+
+// AvailableEvents returns every event listed in available_events.
+func (i *Instance) AvailableEvents() ([]Event, error) {
+	var out []Event
+	err := i.WalkEvents(func(e Event) error {
+		out = append(out, e)
+		return nil
+	})
+	return out, err
+}
+
+// SkipAll can be returned by a WalkEvents callback to stop the walk
+// without an error.
+var SkipAll = errors.New("skip all remaining events")
+
+// WalkEvents calls fn for each event in available_events, reading the file
+// incrementally. If fn returns an error the walk stops and WalkEvents
+// returns it, unless it is SkipAll, in which case WalkEvents returns nil.
+func (i *Instance) WalkEvents(fn func(Event) error) error {
+	f, err := i.open("available_events")
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		system, name, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		if err := fn(Event{System: system, Name: name}); err == SkipAll {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// EventSystems returns the sorted names of the event systems (the
+// directories under events/).
+func (i *Instance) EventSystems() ([]string, error) {
+	entries, err := i.fsys().ReadDir(filepath.Join(i.path, eventDir(Event{})))
+	if err != nil {
+		return nil, i.wrapErr(err)
+	}
+
+	// Skip files such as enable, header_page and header_event.
+	var out []string
+	for _, e := range entries {
+		if e.IsDir() {
+			out = append(out, e.Name())
+		}
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+// AvailableEventsInSystem returns the events in system by listing its
+// events directory, which is cheaper than reading available_events. The
+// system's enable and filter files are skipped.
+func (i *Instance) AvailableEventsInSystem(system string) ([]Event, error) {
+	if system == "" {
+		return nil, fmt.Errorf("%w: system name required", ErrInvalidValue)
+	}
+
+	entries, err := i.fsys().ReadDir(filepath.Join(i.path, eventDir(Event{System: system})))
+	if err != nil {
+		return nil, i.wrapErr(err)
+	}
+
+	var out []Event
+	for _, e := range entries {
+		if e.IsDir() {
+			out = append(out, Event{System: system, Name: e.Name()})
+		}
+	}
+	return out, nil
+}
+
+// EventID returns the numeric ID of e, which identifies its records in
+// trace_pipe_raw and perf.
+func (i *Instance) EventID(e Event) (int, error) {
+	if e.System == "" || e.Name == "" {
+		return 0, fmt.Errorf("%w: event id requires a system and name", ErrInvalidValue)
+	}
+
+	id, err := i.readInt(filepath.Join(eventDir(e), "id"))
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, fmt.Errorf("event %s does not exist: %w", e, err)
+	}
+	return id, err
+}
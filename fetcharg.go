@@ -0,0 +1,570 @@
+package tracefs
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// FetchArg is a probe fetch argument, as described in
+// Documentation/trace/kprobetrace.rst.
+type FetchArg interface {
+	Type() string
+	String() string
+}
+
+// FetchRegister fetches the value of the named register, e.g. "%di".
+// The % prefix is added if name does not already have one.
+func FetchRegister(name string) FetchArg {
+	if !strings.HasPrefix(name, "%") {
+		name = "%" + name
+	}
+	return fetchRegister{register: name}
+}
+
+// argValidator is implemented by fetch arguments that can detect invalid
+// configurations before they are written to the kernel.
+type argValidator interface {
+	validate() error
+}
+
+func validateFetchArg(arg FetchArg) error {
+	if v, ok := arg.(argValidator); ok {
+		return v.validate()
+	}
+	return nil
+}
+
+func validateFetchArgs(args []FetchArg) error {
+	for _, arg := range args {
+		if err := validateFetchArg(arg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// argWrapper is implemented by fetch arguments that modify another argument.
+type argWrapper interface {
+	unwrap() FetchArg
+}
+
+// usesRetval reports whether any of args fetches $retval.
+func usesRetval(args []FetchArg) bool {
+	for _, arg := range args {
+		for arg != nil {
+			if _, ok := arg.(fetchRetval); ok {
+				return true
+			}
+			w, ok := arg.(argWrapper)
+			if !ok {
+				break
+			}
+			arg = w.unwrap()
+		}
+	}
+	return false
+}
+
+type fetchRegister struct {
+	register string
+}
+
+func (f fetchRegister) Type() string {
+	return "register"
+}
+
+func (f fetchRegister) String() string {
+	return f.register
+}
+
+type fetchMemory struct {
+	offset int64
+	inner  FetchArg
+}
+
+// FetchMemory dereferences the address produced by inner plus offset,
+// e.g. FetchMemory(8, FetchMemory(0, reg)) renders as +8(+0(%si)).
+func FetchMemory(offset int64, inner FetchArg) FetchArg {
+	return fetchMemory{
+		offset: offset,
+		inner:  inner,
+	}
+}
+
+func (f fetchMemory) Type() string {
+	return "memory"
+}
+
+func (f fetchMemory) validate() error {
+	return validateFetchArg(f.inner)
+}
+
+func (f fetchMemory) unwrap() FetchArg {
+	return f.inner
+}
+
+func (f fetchMemory) String() string {
+	return fmt.Sprintf("%+d(%s)", f.offset, f.inner.String())
+}
+
+type fetchSymbol struct {
+	symbol string
+	offset int64
+}
+
+// FetchSymbol fetches the memory at a kernel symbol plus offset, rendering
+// as @symbol or @symbol+offset. It is used to read global variables from
+// kprobes.
+func FetchSymbol(symbol string, offset int64) FetchArg {
+	return fetchSymbol{
+		symbol: symbol,
+		offset: offset,
+	}
+}
+
+func (f fetchSymbol) Type() string {
+	return "memory"
+}
+
+func (f fetchSymbol) validate() error {
+	if f.symbol == "" || strings.ContainsAny(f.symbol, " \t\n@+-") {
+		return fmt.Errorf("%w: invalid symbol %q", ErrInvalidValue, f.symbol)
+	}
+	return nil
+}
+
+func (f fetchSymbol) String() string {
+	if f.offset == 0 {
+		return "@" + f.symbol
+	}
+	return fmt.Sprintf("@%s%+d", f.symbol, f.offset)
+}
+
+type fetchAddress struct {
+	addr uint64
+}
+
+// FetchAddress fetches the memory at the absolute kernel address addr,
+// rendering as @0xaddr.
+func FetchAddress(addr uint64) FetchArg {
+	return fetchAddress{addr: addr}
+}
+
+func (f fetchAddress) Type() string {
+	return "memory"
+}
+
+func (f fetchAddress) String() string {
+	return fmt.Sprintf("@0x%x", f.addr)
+}
+
+// ArgType is a fetch argument type suffix.
+type ArgType string
+
+const (
+	ArgU8     ArgType = "u8"
+	ArgU16    ArgType = "u16"
+	ArgU32    ArgType = "u32"
+	ArgU64    ArgType = "u64"
+	ArgS8     ArgType = "s8"
+	ArgS16    ArgType = "s16"
+	ArgS32    ArgType = "s32"
+	ArgS64    ArgType = "s64"
+	ArgX8     ArgType = "x8"
+	ArgX16    ArgType = "x16"
+	ArgX32    ArgType = "x32"
+	ArgX64    ArgType = "x64"
+	ArgString ArgType = "string"
+	// ArgUString is a string read from user space memory.
+	ArgUString ArgType = "ustring"
+	ArgSymbol  ArgType = "symbol"
+)
+
+type typedArg struct {
+	inner FetchArg
+	typ   ArgType
+}
+
+// WithType sets the type of arg, rendering as expr:type. t must be one of
+// the Arg constants or a bitfield type such as "b4@2/8"; other types are
+// rejected when the probe is validated.
+func WithType(arg FetchArg, t ArgType) FetchArg {
+	return typedArg{
+		inner: arg,
+		typ:   t,
+	}
+}
+
+func (f typedArg) Type() string {
+	return string(f.typ)
+}
+
+func (f typedArg) validate() error {
+	switch f.typ {
+	case ArgU8, ArgU16, ArgU32, ArgU64, ArgS8, ArgS16, ArgS32, ArgS64, ArgX8, ArgX16, ArgX32, ArgX64,
+		ArgString, ArgUString, ArgSymbol:
+	default:
+		var width, offset, size int
+		n, err := fmt.Sscanf(string(f.typ), "b%d@%d/%d", &width, &offset, &size)
+		if err != nil || n != 3 || fmt.Sprintf("b%d@%d/%d", width, offset, size) != string(f.typ) {
+			return fmt.Errorf("%w: unknown fetch arg type %q", ErrInvalidValue, f.typ)
+		}
+		return bitfieldArg{inner: f.inner, width: width, offset: offset, size: size}.validate()
+	}
+	return validateFetchArg(f.inner)
+}
+
+func (f typedArg) unwrap() FetchArg {
+	return f.inner
+}
+
+func (f typedArg) String() string {
+	return f.inner.String() + ":" + string(f.typ)
+}
+
+// maxArrayLen is the largest element count the kernel accepts for an
+// array fetch argument.
+const maxArrayLen = 64
+
+type arrayArg struct {
+	inner FetchArg
+	typ   ArgType
+	count int
+}
+
+// FetchArray reads count elements of type elem starting at the address
+// dereferenced by inner, rendering as expr:type[count]. inner must be a
+// memory fetch (FetchMemory, FetchSymbol or FetchAddress), and elem an
+// integer type.
+func FetchArray(inner FetchArg, elem ArgType, count int) FetchArg {
+	return arrayArg{
+		inner: inner,
+		typ:   elem,
+		count: count,
+	}
+}
+
+func (f arrayArg) Type() string {
+	return fmt.Sprintf("%s[%d]", f.typ, f.count)
+}
+
+func (f arrayArg) validate() error {
+	if f.count < 1 || f.count > maxArrayLen {
+		return fmt.Errorf("%w: array length %d must be between 1 and %d", ErrInvalidValue, f.count, maxArrayLen)
+	}
+	switch f.typ {
+	case ArgU8, ArgU16, ArgU32, ArgU64, ArgS8, ArgS16, ArgS32, ArgS64, ArgX8, ArgX16, ArgX32, ArgX64:
+	default:
+		return fmt.Errorf("%w: array element type must be an integer type, got %q", ErrInvalidValue, f.typ)
+	}
+	if f.inner.Type() != "memory" {
+		return fmt.Errorf("%w: arrays can only be fetched from memory, not %s", ErrInvalidValue, f.inner)
+	}
+	return validateFetchArg(f.inner)
+}
+
+func (f arrayArg) unwrap() FetchArg {
+	return f.inner
+}
+
+func (f arrayArg) String() string {
+	return fmt.Sprintf("%s:%s[%d]", f.inner.String(), f.typ, f.count)
+}
+
+type bitfieldArg struct {
+	inner  FetchArg
+	width  int
+	offset int
+	size   int
+}
+
+// FetchBitfield extracts width bits starting at bit offset from a value of
+// size bits (8, 16, 32 or 64) fetched by inner, rendering as
+// expr:bwidth@offset/size.
+func FetchBitfield(inner FetchArg, width, offset, size int) FetchArg {
+	return bitfieldArg{
+		inner:  inner,
+		width:  width,
+		offset: offset,
+		size:   size,
+	}
+}
+
+func (f bitfieldArg) Type() string {
+	return fmt.Sprintf("b%d@%d/%d", f.width, f.offset, f.size)
+}
+
+func (f bitfieldArg) validate() error {
+	switch f.size {
+	case 8, 16, 32, 64:
+	default:
+		return fmt.Errorf("%w: bitfield container size must be 8, 16, 32 or 64 bits, got %d", ErrInvalidValue, f.size)
+	}
+	if f.width < 1 || f.offset < 0 || f.width+f.offset > f.size {
+		return fmt.Errorf("%w: bitfield of %d bits at offset %d does not fit in %d bits", ErrInvalidValue, f.width, f.offset, f.size)
+	}
+	return validateFetchArg(f.inner)
+}
+
+func (f bitfieldArg) unwrap() FetchArg {
+	return f.inner
+}
+
+func (f bitfieldArg) String() string {
+	return f.inner.String() + ":" + f.Type()
+}
+
+// reservedArgNames are field names the kernel already uses for every probe
+// event.
+var reservedArgNames = map[string]bool{
+	"common_type":          true,
+	"common_flags":         true,
+	"common_preempt_count": true,
+	"common_pid":           true,
+	"common_tgid":          true,
+	"__probe_ip":           true,
+	"__probe_ret_ip":       true,
+	"__probe_func":         true,
+}
+
+type namedArg struct {
+	name  string
+	inner FetchArg
+}
+
+// Named gives arg a name, rendering as name=expr. Names must match
+// [a-zA-Z_][a-zA-Z0-9_]*; invalid names are reported when the probe is added.
+func Named(name string, arg FetchArg) FetchArg {
+	return namedArg{
+		name:  name,
+		inner: arg,
+	}
+}
+
+func (f namedArg) Type() string {
+	return f.inner.Type()
+}
+
+func (f namedArg) validate() error {
+	if !validName(f.name) {
+		return fmt.Errorf("%w: invalid fetch arg name %q", ErrInvalidValue, f.name)
+	}
+	if reservedArgNames[f.name] {
+		return fmt.Errorf("%w: fetch arg name %q is reserved", ErrInvalidValue, f.name)
+	}
+	return validateFetchArg(f.inner)
+}
+
+func (f namedArg) unwrap() FetchArg {
+	return f.inner
+}
+
+func (f namedArg) String() string {
+	return f.name + "=" + f.inner.String()
+}
+
+// validName reports whether s is a valid kernel event or argument name.
+func validName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, c := range s {
+		switch {
+		case c == '_', c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+		case c >= '0' && c <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+type fetchRetval struct{}
+
+// FetchRetval fetches the return value of the probed function. It is only
+// valid on return probes and may be combined with WithType, e.g. $retval:s32.
+func FetchRetval() FetchArg {
+	return fetchRetval{}
+}
+
+func (f fetchRetval) Type() string {
+	return "retval"
+}
+
+func (f fetchRetval) String() string {
+	return "$retval"
+}
+
+// maxStackN is the largest $stackN index accepted. The kernel limits N to
+// the number of words in a kernel stack (16KB on x86_64).
+const maxStackN = 2048
+
+type fetchStack struct {
+	n   uint
+	all bool
+}
+
+// FetchStack fetches the stack address ($stack).
+func FetchStack() FetchArg {
+	return fetchStack{all: true}
+}
+
+// FetchStackN fetches the Nth entry of the stack ($stackN). What each entry
+// holds depends on the architecture's calling convention; on x86_64
+// $stack0 is the return address and arguments beyond the sixth start at
+// $stack1.
+func FetchStackN(n uint) FetchArg {
+	return fetchStack{n: n}
+}
+
+func (f fetchStack) Type() string {
+	return "stack"
+}
+
+func (f fetchStack) validate() error {
+	if !f.all && f.n >= maxStackN {
+		return fmt.Errorf("%w: $stack%d exceeds max stack index %d", ErrInvalidValue, f.n, maxStackN-1)
+	}
+	return nil
+}
+
+func (f fetchStack) String() string {
+	if f.all {
+		return "$stack"
+	}
+	return fmt.Sprintf("$stack%d", f.n)
+}
+
+type fetchComm struct{}
+
+// FetchComm fetches the current task's command name ($comm).
+func FetchComm() FetchArg {
+	return fetchComm{}
+}
+
+func (f fetchComm) Type() string {
+	return "comm"
+}
+
+func (f fetchComm) String() string {
+	return "$comm"
+}
+
+type fetchImmediate struct {
+	value string
+}
+
+// FetchImmediate fetches the constant v, rendered in decimal (\v).
+func FetchImmediate(v uint64) FetchArg {
+	return fetchImmediate{value: strconv.FormatUint(v, 10)}
+}
+
+// FetchImmediateHex fetches the constant v, rendered in hex (\0xv).
+func FetchImmediateHex(v uint64) FetchArg {
+	return fetchImmediate{value: "0x" + strconv.FormatUint(v, 16)}
+}
+
+// FetchImmediateSigned fetches the signed constant v.
+func FetchImmediateSigned(v int64) FetchArg {
+	return fetchImmediate{value: strconv.FormatInt(v, 10)}
+}
+
+func (f fetchImmediate) Type() string {
+	return "immediate"
+}
+
+func (f fetchImmediate) String() string {
+	return `\` + f.value
+}
+
+type rawFetchArg struct {
+	expr string
+}
+
+func (f rawFetchArg) Type() string {
+	return "raw"
+}
+
+func (f rawFetchArg) String() string {
+	return f.expr
+}
+
+// parseFetchArg parses a fetch argument as listed in uprobe_events or
+// kprobe_events. It is the inverse of the FetchArg String methods;
+// expressions it does not recognize are kept verbatim.
+func parseFetchArg(s string) FetchArg {
+	if name, rest, ok := strings.Cut(s, "="); ok && validName(name) {
+		return Named(name, parseFetchArg(rest))
+	}
+
+	// The type suffix follows the last ':' outside of any parentheses.
+	depth := 0
+	for n := len(s) - 1; n >= 0; n-- {
+		switch s[n] {
+		case ')':
+			depth++
+		case '(':
+			depth--
+		case ':':
+			if depth == 0 {
+				return parseTypedArg(parseFetchArg(s[:n]), s[n+1:])
+			}
+		}
+	}
+
+	switch {
+	case s == "$retval":
+		return FetchRetval()
+	case s == "$comm":
+		return FetchComm()
+	case s == "$stack":
+		return FetchStack()
+	case strings.HasPrefix(s, "$stack"):
+		if n, err := strconv.ParseUint(s[len("$stack"):], 10, 32); err == nil {
+			return FetchStackN(uint(n))
+		}
+	case strings.HasPrefix(s, "%"):
+		return fetchRegister{register: s}
+	case strings.HasPrefix(s, "@0x"):
+		if addr, err := strconv.ParseUint(s[1:], 0, 64); err == nil {
+			return FetchAddress(addr)
+		}
+	case strings.HasPrefix(s, "@") && !strings.HasPrefix(s, "@+"):
+		sym := s[1:]
+		var off int64
+		if idx := strings.IndexAny(sym, "+-"); idx > 0 {
+			n, err := strconv.ParseInt(sym[idx:], 0, 64)
+			if err != nil {
+				break
+			}
+			sym, off = sym[:idx], n
+		}
+		return FetchSymbol(sym, off)
+	case strings.HasPrefix(s, `\`):
+		return fetchImmediate{value: s[1:]}
+	case (strings.HasPrefix(s, "+") || strings.HasPrefix(s, "-")) && strings.HasSuffix(s, ")"):
+		if open := strings.Index(s, "("); open > 0 {
+			if off, err := strconv.ParseInt(s[:open], 0, 64); err == nil {
+				return FetchMemory(off, parseFetchArg(s[open+1:len(s)-1]))
+			}
+		}
+	}
+
+	return rawFetchArg{expr: s}
+}
+
+// parseTypedArg applies the type suffix t, e.g. "u32", "x8[4]" or
+// "b4@2/8", to arg.
+func parseTypedArg(arg FetchArg, t string) FetchArg {
+	if open := strings.Index(t, "["); open > 0 && strings.HasSuffix(t, "]") {
+		if n, err := strconv.Atoi(t[open+1 : len(t)-1]); err == nil {
+			return FetchArray(arg, ArgType(t[:open]), n)
+		}
+	}
+	var width, offset, size int
+	if n, err := fmt.Sscanf(t, "b%d@%d/%d", &width, &offset, &size); err == nil && n == 3 {
+		return FetchBitfield(arg, width, offset, size)
+	}
+	return WithType(arg, ArgType(t))
+}
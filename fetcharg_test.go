@@ -0,0 +1,76 @@
+package tracefs
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseFetchArg(t *testing.T) {
+	reg := FetchRegister("di")
+	tests := []struct {
+		expr string
+		want FetchArg
+	}{
+		{"%di", reg},
+		{"$retval", FetchRetval()},
+		{"$comm", FetchComm()},
+		{"$stack", FetchStack()},
+		{"$stack3", FetchStackN(3)},
+		{`\42`, FetchImmediate(42)},
+		{`\0x2a`, FetchImmediateHex(42)},
+		{"@0x1000", FetchAddress(0x1000)},
+		{"@jiffies", FetchSymbol("jiffies", 0)},
+		{"@jiffies+16", FetchSymbol("jiffies", 16)},
+		{"@jiffies-8", FetchSymbol("jiffies", -8)},
+		{"+8(%di)", FetchMemory(8, reg)},
+		{"-4(+0(%di))", FetchMemory(-4, FetchMemory(0, reg))},
+		{"%di:u32", WithType(reg, ArgU32)},
+		{"+0(%di):string", WithType(FetchMemory(0, reg), ArgString)},
+		{"+0(%di):x8[4]", FetchArray(FetchMemory(0, reg), ArgX8, 4)},
+		{"%di:b4@2/32", FetchBitfield(reg, 4, 2, 32)},
+		{"len=%di:s64", Named("len", WithType(reg, ArgS64))},
+		{"path=+0(+8(%si)):string", Named("path", WithType(FetchMemory(0, FetchMemory(8, FetchRegister("si"))), ArgString))},
+		{"$unknown", rawFetchArg{expr: "$unknown"}},
+	}
+
+	for _, tt := range tests {
+		got := parseFetchArg(tt.expr)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseFetchArg(%q) = %#v, want %#v", tt.expr, got, tt.want)
+		}
+		if s := got.String(); s != tt.expr {
+			t.Errorf("parseFetchArg(%q).String() = %q", tt.expr, s)
+		}
+	}
+}
+
+func TestUsesRetval(t *testing.T) {
+	if !usesRetval([]FetchArg{Named("ret", WithType(FetchRetval(), ArgS32))}) {
+		t.Error("usesRetval missed a named, typed $retval")
+	}
+	if usesRetval([]FetchArg{FetchMemory(0, FetchRegister("di"))}) {
+		t.Error("usesRetval reported $retval for a memory fetch")
+	}
+}
+
+func TestWithTypeValidate(t *testing.T) {
+	reg := FetchRegister("di")
+	for _, typ := range []ArgType{ArgU8, ArgS64, ArgX32, ArgString, ArgUString, ArgSymbol, "b4@2/32"} {
+		if err := validateFetchArg(WithType(reg, typ)); err != nil {
+			t.Errorf("WithType(%q): %v", typ, err)
+		}
+	}
+	for _, typ := range []ArgType{"", "u128", "int", "String", "b4@2", "b4@2/32x", "b40@0/32"} {
+		if err := validateFetchArg(WithType(reg, typ)); !errors.Is(err, ErrInvalidValue) {
+			t.Errorf("WithType(%q) validate = %v, want ErrInvalidValue", typ, err)
+		}
+	}
+
+	// Types parsed from the kernel's probe listings validate.
+	for _, expr := range []string{"%di:u32", "+0(%di):string", "+0(%si):ustring", "%di:b4@2/32", "@jiffies:symbol"} {
+		if err := validateFetchArg(parseFetchArg(expr)); err != nil {
+			t.Errorf("parseFetchArg(%q) validate: %v", expr, err)
+		}
+	}
+}
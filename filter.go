@@ -0,0 +1,43 @@
+package tracefs
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"syscall"
+)
+
+// SetEventFilter sets the filter expression for e, e.g. "prev_pid == 1".
+// If the kernel rejects expr, the returned error includes the parse error
+// from error_log or, on older kernels, from the filter file.
+func (i *Instance) SetEventFilter(e Event, expr string) error {
+	p := filepath.Join(eventDir(e), "filter")
+	err := i.writeFile(p, []byte(expr))
+	if annotated := i.annotateErr(err, expr); annotated != err {
+		return annotated
+	}
+	if errors.Is(err, syscall.EINVAL) {
+		msg, readErr := i.readFile(p)
+		if readErr == nil && len(msg) > 0 {
+			return fmt.Errorf("invalid filter %q for %s: %s: %w", expr, e, msg, err)
+		}
+	}
+	return err
+}
+
+// EventFilter returns the filter expression for e, or "" if there is none.
+func (i *Instance) EventFilter(e Event) (string, error) {
+	data, err := i.readFile(filepath.Join(eventDir(e), "filter"))
+	if err != nil {
+		return "", err
+	}
+	if string(data) == "none" {
+		return "", nil
+	}
+	return string(data), nil
+}
+
+// ClearEventFilter removes the filter for e.
+func (i *Instance) ClearEventFilter(e Event) error {
+	return i.writeFile(filepath.Join(eventDir(e), "filter"), []byte("0"))
+}
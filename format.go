@@ -0,0 +1,148 @@
+package tracefs
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// EventFormat describes the binary layout of an event, as read from its
+// format file.
+type EventFormat struct {
+	Name string
+	ID   int
+	// CommonFields are the fields shared by every event (common_type,
+	// common_pid, etc.).
+	CommonFields []FormatField
+	// Fields are the event specific fields.
+	Fields   []FormatField
+	PrintFmt string
+}
+
+// FormatField describes a single field of an event record.
+type FormatField struct {
+	Name    string
+	Type    string
+	Offset  int
+	Size    int
+	Signed  bool
+	IsArray bool
+	// ArrayLen is the number of elements for fixed size arrays. It is 0 for
+	// dynamic (__data_loc) arrays.
+	ArrayLen int
+}
+
+// EventFormat returns the parsed format file for e.
+func (i *Instance) EventFormat(e Event) (*EventFormat, error) {
+	data, err := i.readFile(filepath.Join(eventDir(e), "format"))
+	if err != nil {
+		return nil, err
+	}
+	return parseEventFormat(data)
+}
+
+// parseEventFormat parses a format file. The common fields are separated
+// from the event specific fields by a blank line.
+func parseEventFormat(data []byte) (*EventFormat, error) {
+	var (
+		f       EventFormat
+		common  = true
+		inField bool
+	)
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		switch {
+		case strings.HasPrefix(line, "name:"):
+			f.Name = strings.TrimSpace(strings.TrimPrefix(line, "name:"))
+		case strings.HasPrefix(line, "ID:"):
+			id, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "ID:")))
+			if err != nil {
+				return nil, fmt.Errorf("invalid format ID line %q: %w", line, err)
+			}
+			f.ID = id
+		case strings.HasPrefix(line, "print fmt:"):
+			f.PrintFmt = strings.TrimSpace(strings.TrimPrefix(line, "print fmt:"))
+		case strings.HasPrefix(line, "field:"):
+			field, err := parseFormatField(line)
+			if err != nil {
+				return nil, err
+			}
+			inField = true
+			if common {
+				f.CommonFields = append(f.CommonFields, field)
+			} else {
+				f.Fields = append(f.Fields, field)
+			}
+		case line == "":
+			if inField {
+				common = false
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	// Files without a blank separator (e.g. header_page) have no common
+	// section.
+	if common {
+		f.Fields, f.CommonFields = f.CommonFields, nil
+	}
+
+	return &f, nil
+}
+
+// parseFormatField parses a line of the form:
+//
+//	field:unsigned char prev_comm[16];	offset:8;	size:16;	signed:0;
+func parseFormatField(line string) (FormatField, error) {
+	var field FormatField
+	for _, part := range strings.Split(line, ";") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+
+		var err error
+		switch key {
+		case "field", "field special":
+			field.Type, field.Name = splitFieldDecl(value)
+		case "offset":
+			field.Offset, err = strconv.Atoi(value)
+		case "size":
+			field.Size, err = strconv.Atoi(value)
+		case "signed":
+			field.Signed = value == "1"
+		}
+		if err != nil {
+			return field, fmt.Errorf("invalid format field %q: %w", line, err)
+		}
+	}
+
+	if idx := strings.Index(field.Name, "["); idx >= 0 {
+		field.IsArray = true
+		field.ArrayLen, _ = strconv.Atoi(strings.TrimSuffix(field.Name[idx+1:], "]"))
+		field.Name = field.Name[:idx]
+	} else if strings.HasSuffix(field.Type, "[]") {
+		field.IsArray = true
+	}
+
+	return field, nil
+}
+
+// splitFieldDecl splits a C declaration like "unsigned long ip" into its
+// type and name.
+func splitFieldDecl(decl string) (typ, name string) {
+	idx := strings.LastIndexAny(decl, " \t")
+	if idx < 0 {
+		return "", decl
+	}
+	return strings.TrimSpace(decl[:idx]), decl[idx+1:]
+}
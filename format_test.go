@@ -0,0 +1,59 @@
+package tracefs
+
+import (
+	"reflect"
+	"testing"
+)
+
+const sampleFormat = `name: sched_switch
+ID: 316
+format:
+	field:unsigned short common_type;	offset:0;	size:2;	signed:0;
+	field:unsigned char common_flags;	offset:2;	size:1;	signed:0;
+	field:unsigned char common_preempt_count;	offset:3;	size:1;	signed:0;
+	field:int common_pid;	offset:4;	size:4;	signed:1;
+
+	field:char prev_comm[16];	offset:8;	size:16;	signed:0;
+	field:pid_t prev_pid;	offset:24;	size:4;	signed:1;
+	field:__data_loc char[] name;	offset:28;	size:4;	signed:0;
+
+print fmt: "prev_comm=%s prev_pid=%d", REC->prev_comm, REC->prev_pid
+`
+
+func TestParseEventFormat(t *testing.T) {
+	want := &EventFormat{
+		Name: "sched_switch",
+		ID:   316,
+		CommonFields: []FormatField{
+			{Name: "common_type", Type: "unsigned short", Offset: 0, Size: 2},
+			{Name: "common_flags", Type: "unsigned char", Offset: 2, Size: 1},
+			{Name: "common_preempt_count", Type: "unsigned char", Offset: 3, Size: 1},
+			{Name: "common_pid", Type: "int", Offset: 4, Size: 4, Signed: true},
+		},
+		Fields: []FormatField{
+			{Name: "prev_comm", Type: "char", Offset: 8, Size: 16, IsArray: true, ArrayLen: 16},
+			{Name: "prev_pid", Type: "pid_t", Offset: 24, Size: 4, Signed: true},
+			{Name: "name", Type: "__data_loc char[]", Offset: 28, Size: 4, IsArray: true},
+		},
+		PrintFmt: `"prev_comm=%s prev_pid=%d", REC->prev_comm, REC->prev_pid`,
+	}
+
+	got, err := parseEventFormat([]byte(sampleFormat))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseEventFormat:\n got %+v\nwant %+v", got, want)
+	}
+}
+
+func TestParseEventFormatInvalid(t *testing.T) {
+	for _, input := range []string{
+		"name: foo\nID: abc\n",
+		"format:\n\tfield:int x;\toffset:zero;\tsize:4;\tsigned:1;\n",
+	} {
+		if _, err := parseEventFormat([]byte(input)); err == nil {
+			t.Errorf("parseEventFormat(%q) succeeded", input)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package tracefs
+
+import (
+	"errors"
+	"io"
+	"os"
+	"syscall"
+	"time"
+)
+
+// FS is the filesystem backend used by an Instance. All names are full
+// paths, including the instance path. The default backend uses the os
+// package; tests can supply a fake to run without a mounted tracefs.
+type FS interface {
+	OpenFile(name string, flag int, perm os.FileMode) (File, error)
+	ReadDir(name string) ([]os.DirEntry, error)
+	Stat(name string) (os.FileInfo, error)
+	Mkdir(name string, perm os.FileMode) error
+	Remove(name string) error
+}
+
+// File is an open file returned by an FS.
+type File interface {
+	io.Reader
+	io.Writer
+	io.Closer
+}
+
+// OSFS is the FS backed by the real filesystem.
+var OSFS FS = osFS{}
+
+type osFS struct{}
+
+func (osFS) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	return os.OpenFile(name, flag, perm)
+}
+
+func (osFS) ReadDir(name string) ([]os.DirEntry, error) {
+	return os.ReadDir(name)
+}
+
+func (osFS) Stat(name string) (os.FileInfo, error) {
+	return os.Stat(name)
+}
+
+func (osFS) Mkdir(name string, perm os.FileMode) error {
+	return os.Mkdir(name, perm)
+}
+
+func (osFS) Remove(name string) error {
+	return os.Remove(name)
+}
+
+// fsys returns the instance's FS, defaulting to OSFS.
+func (i *Instance) fsys() FS {
+	if i.cfg.fs == nil {
+		return OSFS
+	}
+	return i.cfg.fs
+}
+
+// maxTransientRetries bounds how often an operation failing with EAGAIN is
+// retried. EINTR is always retried.
+const maxTransientRetries = 10
+
+// transientRetryDelay is the pause between EAGAIN retries.
+var transientRetryDelay = time.Millisecond
+
+// retryTransient calls fn until it returns an error other than EINTR or
+// EAGAIN, retrying EAGAIN at most maxTransientRetries times.
+func retryTransient(fn func() error) error {
+	for again := 0; ; {
+		err := fn()
+		switch {
+		case errors.Is(err, syscall.EINTR):
+			continue
+		case errors.Is(err, syscall.EAGAIN) && again < maxTransientRetries:
+			again++
+			time.Sleep(transientRetryDelay)
+			continue
+		}
+		return err
+	}
+}
+
+func openRetry(fsys FS, name string, flag int, perm os.FileMode) (File, error) {
+	var f File
+	err := retryTransient(func() error {
+		var err error
+		f, err = fsys.OpenFile(name, flag, perm)
+		return err
+	})
+	return f, err
+}
+
+func readPath(fsys FS, name string) ([]byte, error) {
+	f, err := openRetry(fsys, name, os.O_RDONLY, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var (
+		out []byte
+		buf = make([]byte, 4096)
+	)
+	for {
+		var n int
+		err := retryTransient(func() error {
+			var err error
+			n, err = f.Read(buf)
+			return err
+		})
+		out = append(out, buf[:n]...)
+		if err == io.EOF {
+			return out, nil
+		} else if err != nil {
+			return out, err
+		}
+	}
+}
+
+// writePath writes b to name. Tracefs control files must already exist, so
+// the file is never created. If truncate is set the file is opened with
+// O_TRUNC, which for list files such as set_event or set_ftrace_filter
+// clears the existing entries. Transient EINTR and EAGAIN errors are retried
+// and a partial write is continued with the remainder.
+func (i *Instance) writePath(name string, b []byte, truncate bool) error {
+	flag := os.O_WRONLY
+	if truncate {
+		flag |= os.O_TRUNC
+	}
+	return i.writePathFlag(name, b, flag)
+}
+
+func (i *Instance) writePathFlag(name string, b []byte, flag int) error {
+	defer i.lock()()
+
+	f, err := openRetry(i.fsys(), name, flag, i.writeMode())
+	if err != nil {
+		return err
+	}
+	err = writeAll(f, b)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// writeAll writes b to f, retrying transient errors and continuing partial
+// writes.
+func writeAll(f File, b []byte) error {
+	return retryTransient(func() error {
+		for {
+			n, err := f.Write(b)
+			b = b[n:]
+			if err != nil || len(b) == 0 {
+				return err
+			}
+			if n == 0 {
+				return io.ErrShortWrite
+			}
+		}
+	})
+}
@@ -0,0 +1,321 @@
+package tracefs
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// memFS is an in-memory FS for tests. Files are created from a fixture map
+// and every write is recorded. Writes to a file opened without O_APPEND
+// replace its contents, which matches how tracefs treats most control
+// files.
+type memFS struct {
+	mu     sync.Mutex
+	files  map[string][]byte
+	dirs   map[string]bool
+	writes []memWrite
+	// writeErrs holds errors returned, in order, by the next writes to a
+	// path, for simulating failures. A nil entry lets that write succeed.
+	writeErrs map[string][]error
+}
+
+type memWrite struct {
+	Name string
+	Data string
+}
+
+// newMemFS returns a memFS holding files, keyed by full path. The parent
+// directories of each file are created.
+func newMemFS(files map[string]string) *memFS {
+	m := &memFS{
+		files:     map[string][]byte{},
+		dirs:      map[string]bool{"/": true},
+		writeErrs: map[string][]error{},
+	}
+	for name, data := range files {
+		m.addFile(name, data)
+	}
+	return m
+}
+
+// newTracefs returns a memFS with a minimal tracefs root at root.
+func newTracefs(root string, files map[string]string) *memFS {
+	m := newMemFS(files)
+	m.addFile(filepath.Join(root, "tracing_on"), "1\n")
+	if !m.exists(filepath.Join(root, "current_tracer")) {
+		m.addFile(filepath.Join(root, "current_tracer"), "nop\n")
+	}
+	m.dirs[filepath.Join(root, "instances")] = true
+	return m
+}
+
+func (m *memFS) addFile(name, data string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	name = filepath.Clean(name)
+	m.files[name] = []byte(data)
+	for dir := filepath.Dir(name); !m.dirs[dir]; dir = filepath.Dir(dir) {
+		m.dirs[dir] = true
+	}
+}
+
+func (m *memFS) exists(name string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, ok := m.files[filepath.Clean(name)]
+	return ok || m.dirs[filepath.Clean(name)]
+}
+
+// content returns the contents of name.
+func (m *memFS) content(name string) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return string(m.files[filepath.Clean(name)])
+}
+
+// written returns the data of each write to name, in order.
+func (m *memFS) written(name string) []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []string
+	for _, w := range m.writes {
+		if w.Name == filepath.Clean(name) {
+			out = append(out, w.Data)
+		}
+	}
+	return out
+}
+
+func (m *memFS) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	name = filepath.Clean(name)
+
+	if m.dirs[name] {
+		return nil, &os.PathError{Op: "open", Path: name, Err: syscall.EISDIR}
+	}
+	data, ok := m.files[name]
+	if !ok {
+		if flag&os.O_CREATE == 0 || !m.dirs[filepath.Dir(name)] {
+			return nil, &os.PathError{Op: "open", Path: name, Err: syscall.ENOENT}
+		}
+		m.files[name] = nil
+	}
+	if flag&os.O_TRUNC != 0 {
+		m.files[name] = nil
+	}
+
+	f := &memFile{fs: m, name: name, r: bytes.NewReader(append([]byte(nil), data...))}
+	if flag&os.O_APPEND != 0 {
+		f.base = m.files[name]
+	}
+	return f, nil
+}
+
+func (m *memFS) ReadDir(name string) ([]os.DirEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	name = filepath.Clean(name)
+	if !m.dirs[name] {
+		return nil, &os.PathError{Op: "readdir", Path: name, Err: syscall.ENOENT}
+	}
+
+	var out []os.DirEntry
+	for f, data := range m.files {
+		if filepath.Dir(f) == name {
+			out = append(out, fs.FileInfoToDirEntry(memInfo{name: filepath.Base(f), size: int64(len(data))}))
+		}
+	}
+	for d := range m.dirs {
+		if d != name && filepath.Dir(d) == name {
+			out = append(out, fs.FileInfoToDirEntry(memInfo{name: filepath.Base(d), dir: true}))
+		}
+	}
+	sort.Slice(out, func(a, b int) bool { return out[a].Name() < out[b].Name() })
+	return out, nil
+}
+
+func (m *memFS) Stat(name string) (os.FileInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	name = filepath.Clean(name)
+	if m.dirs[name] {
+		return memInfo{name: filepath.Base(name), dir: true}, nil
+	}
+	if data, ok := m.files[name]; ok {
+		return memInfo{name: filepath.Base(name), size: int64(len(data))}, nil
+	}
+	return nil, &os.PathError{Op: "stat", Path: name, Err: syscall.ENOENT}
+}
+
+func (m *memFS) Mkdir(name string, perm os.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	name = filepath.Clean(name)
+	if _, ok := m.files[name]; ok || m.dirs[name] {
+		return &os.PathError{Op: "mkdir", Path: name, Err: syscall.EEXIST}
+	}
+	if !m.dirs[filepath.Dir(name)] {
+		return &os.PathError{Op: "mkdir", Path: name, Err: syscall.ENOENT}
+	}
+	m.dirs[name] = true
+	return nil
+}
+
+func (m *memFS) Remove(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	name = filepath.Clean(name)
+	if _, ok := m.files[name]; ok {
+		delete(m.files, name)
+		return nil
+	}
+	if !m.dirs[name] {
+		return &os.PathError{Op: "remove", Path: name, Err: syscall.ENOENT}
+	}
+	// Like an instance directory, a directory is removed with its files.
+	for f := range m.files {
+		if strings.HasPrefix(f, name+"/") {
+			delete(m.files, f)
+		}
+	}
+	for d := range m.dirs {
+		if d == name || strings.HasPrefix(d, name+"/") {
+			delete(m.dirs, d)
+		}
+	}
+	return nil
+}
+
+type memFile struct {
+	fs   *memFS
+	name string
+	r    *bytes.Reader
+	// base is the content kept before the writes of an O_APPEND file.
+	base []byte
+	buf  []byte
+}
+
+func (f *memFile) Read(b []byte) (int, error) {
+	return f.r.Read(b)
+}
+
+func (f *memFile) Write(b []byte) (int, error) {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+	if errs := f.fs.writeErrs[f.name]; len(errs) > 0 {
+		f.fs.writeErrs[f.name] = errs[1:]
+		if errs[0] != nil {
+			return 0, &os.PathError{Op: "write", Path: f.name, Err: errs[0]}
+		}
+	}
+	f.fs.writes = append(f.fs.writes, memWrite{Name: f.name, Data: string(b)})
+	f.buf = append(f.buf, b...)
+	f.fs.files[f.name] = append(append([]byte(nil), f.base...), f.buf...)
+	return len(b), nil
+}
+
+func (f *memFile) Close() error {
+	return nil
+}
+
+type memInfo struct {
+	name string
+	size int64
+	dir  bool
+}
+
+func (fi memInfo) Name() string { return fi.name }
+func (fi memInfo) Size() int64  { return fi.size }
+func (fi memInfo) Mode() os.FileMode {
+	if fi.dir {
+		return os.ModeDir | 0755
+	}
+	return 0644
+}
+func (fi memInfo) ModTime() time.Time { return time.Time{} }
+func (fi memInfo) IsDir() bool        { return fi.dir }
+func (fi memInfo) Sys() any           { return nil }
+
+func TestWithFS(t *testing.T) {
+	fsys := newTracefs("/t", nil)
+	i := RootInstance("/t", WithFS(fsys))
+
+	tracer, err := i.CurrentTracer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tracer != NopTracer {
+		t.Errorf("CurrentTracer = %q, want %q", tracer, NopTracer)
+	}
+
+	if err := i.SetTracer(FunctionTracer); err != nil {
+		t.Fatal(err)
+	}
+	if got := fsys.content("/t/current_tracer"); got != string(FunctionTracer) {
+		t.Errorf("current_tracer = %q, want %q", got, FunctionTracer)
+	}
+}
+
+func TestWithFSNotMounted(t *testing.T) {
+	i := RootInstance("/t", WithFS(newMemFS(nil)))
+	if _, err := i.CurrentTracer(); !errors.Is(err, ErrNotMounted) {
+		t.Errorf("CurrentTracer error = %v, want ErrNotMounted", err)
+	}
+}
+
+func TestWriteRetriesTransient(t *testing.T) {
+	defer func(d time.Duration) { transientRetryDelay = d }(transientRetryDelay)
+	transientRetryDelay = 0
+
+	fsys := newTracefs("/t", nil)
+	fsys.writeErrs["/t/current_tracer"] = []error{syscall.EINTR, syscall.EAGAIN}
+	i := RootInstance("/t", WithFS(fsys))
+
+	if err := i.SetTracer(FunctionTracer); err != nil {
+		t.Fatal(err)
+	}
+	if got := fsys.written("/t/current_tracer"); len(got) != 1 || got[0] != string(FunctionTracer) {
+		t.Errorf("writes = %q, want one write of %q", got, FunctionTracer)
+	}
+}
+
+type shortWriter struct {
+	bytes.Buffer
+	limit int
+}
+
+func (w *shortWriter) Write(b []byte) (int, error) {
+	if len(b) > w.limit {
+		b = b[:w.limit]
+	}
+	return w.Buffer.Write(b)
+}
+
+func (w *shortWriter) Read(b []byte) (int, error) { return 0, io.EOF }
+func (w *shortWriter) Close() error               { return nil }
+
+func TestWriteAllContinuesShortWrites(t *testing.T) {
+	w := &shortWriter{limit: 3}
+	if err := writeAll(w, []byte("p:uprobes/foo /bin/foo:0x10")); err != nil {
+		t.Fatal(err)
+	}
+	if got := w.String(); got != "p:uprobes/foo /bin/foo:0x10" {
+		t.Errorf("wrote %q", got)
+	}
+
+	w = &shortWriter{limit: 0}
+	if err := writeAll(w, []byte("x")); !errors.Is(err, io.ErrShortWrite) {
+		t.Errorf("writeAll with no progress = %v, want io.ErrShortWrite", err)
+	}
+}
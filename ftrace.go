@@ -0,0 +1,315 @@
+package tracefs
+
+import (
+	"bufio"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	ftraceFilterPath  = "set_ftrace_filter"
+	ftraceNotracePath = "set_ftrace_notrace"
+)
+
+// SetFtraceFilter limits the function tracer to functions matching
+// patterns, replacing any existing filter. Patterns may use the globs
+// "foo*", "*foo", "*foo*" and "foo*bar", and the module form ":mod:ext4" or
+// "*:mod:ext4". An empty list clears the filter so all functions are traced.
+func (i *Instance) SetFtraceFilter(patterns []string) error {
+	return i.replaceFile(ftraceFilterPath, []byte(strings.Join(patterns, "\n")))
+}
+
+// AddFtraceFilter adds patterns to the existing function filter.
+func (i *Instance) AddFtraceFilter(patterns ...string) error {
+	return i.appendLine(ftraceFilterPath, strings.Join(patterns, "\n"))
+}
+
+// FtraceFilter returns the functions in the function filter. It returns an
+// empty list when all functions are traced.
+func (i *Instance) FtraceFilter() ([]string, error) {
+	return i.readFilterFile(ftraceFilterPath)
+}
+
+// SetFtraceNotrace excludes functions matching patterns from the function
+// tracer, replacing the existing list. Patterns use the same syntax as
+// SetFtraceFilter, e.g. "*lock*". An empty list clears it.
+func (i *Instance) SetFtraceNotrace(patterns []string) error {
+	return i.replaceFile(ftraceNotracePath, []byte(strings.Join(patterns, "\n")))
+}
+
+// AddFtraceNotrace adds patterns to the existing notrace list.
+func (i *Instance) AddFtraceNotrace(patterns ...string) error {
+	return i.appendLine(ftraceNotracePath, strings.Join(patterns, "\n"))
+}
+
+// FtraceNotrace returns the functions excluded from the function tracer.
+func (i *Instance) FtraceNotrace() ([]string, error) {
+	return i.readFilterFile(ftraceNotracePath)
+}
+
+// ftraceCommands are the set_ftrace_filter commands, mapped to whether
+// they accept a count.
+var ftraceCommands = map[string]bool{
+	"mod":           false,
+	"traceon":       true,
+	"traceoff":      true,
+	"snapshot":      true,
+	"enable_event":  true,
+	"disable_event": true,
+	"dump":          false,
+	"cpudump":       false,
+	"stacktrace":    true,
+}
+
+// AddFtraceCommand attaches a command to the functions matching pattern,
+// writing pattern:command[:count] to set_ftrace_filter. The kernel supports:
+//
+//   - mod:<module> limits pattern to a module (no count).
+//   - traceon, traceoff turn tracing on or off when a function is hit.
+//   - snapshot takes a snapshot when a function is hit.
+//   - enable_event:<system>:<event>, disable_event:<system>:<event> toggle
+//     an event when a function is hit.
+//   - dump, cpudump dump the whole or the current cpu's buffer (no count).
+//   - stacktrace records a stack trace when a function is hit.
+//
+// A count > 0 limits how many times the command runs; 0 means always.
+// Commands do not change which functions the function tracer traces.
+func (i *Instance) AddFtraceCommand(pattern, command string, count int) error {
+	cmd, err := ftraceCommand(pattern, command, count)
+	if err != nil {
+		return err
+	}
+	return i.annotateErr(i.appendLine(ftraceFilterPath, cmd), cmd)
+}
+
+// RemoveFtraceCommand removes a command added with AddFtraceCommand.
+func (i *Instance) RemoveFtraceCommand(pattern, command string) error {
+	cmd, err := ftraceCommand(pattern, command, 0)
+	if err != nil {
+		return err
+	}
+	return i.appendLine(ftraceFilterPath, "!"+cmd)
+}
+
+func ftraceCommand(pattern, command string, count int) (string, error) {
+	if pattern == "" {
+		return "", fmt.Errorf("%w: ftrace command pattern must not be empty", ErrInvalidValue)
+	}
+	name, arg, _ := strings.Cut(command, ":")
+	takesCount, ok := ftraceCommands[name]
+	if !ok {
+		return "", fmt.Errorf("%w: unknown ftrace command %q", ErrInvalidValue, name)
+	}
+	switch name {
+	case "mod", "enable_event", "disable_event":
+		if arg == "" {
+			return "", fmt.Errorf("%w: ftrace command %s requires an argument", ErrInvalidValue, name)
+		}
+	}
+	if count < 0 || (count > 0 && !takesCount) {
+		return "", fmt.Errorf("%w: invalid count %d for ftrace command %s", ErrInvalidValue, count, name)
+	}
+
+	cmd := pattern + ":" + command
+	if count > 0 {
+		cmd += ":" + strconv.Itoa(count)
+	}
+	return cmd, nil
+}
+
+// AvailableFilterFunctions returns the functions that can be used in
+// set_ftrace_filter and set_ftrace_notrace. Module annotations (e.g.
+// "[ext4]") are dropped.
+func (i *Instance) AvailableFilterFunctions() ([]string, error) {
+	return i.FilterFunctionsContaining("")
+}
+
+// FilterFunctionsContaining returns the available filter functions whose
+// name contains substr. The file can be very large, so this avoids building
+// the full list when only a subset is needed.
+func (i *Instance) FilterFunctionsContaining(substr string) ([]string, error) {
+	var out []string
+	err := i.walkFilterFunctions(func(name string) {
+		if strings.Contains(name, substr) {
+			out = append(out, name)
+		}
+	})
+	return out, err
+}
+
+// MatchFilterPattern reports whether the function name matches pattern as
+// the kernel does for set_ftrace_filter and set_ftrace_notrace: "foo*",
+// "*foo" and "*foo*" match prefixes, suffixes and substrings, and patterns
+// with other wildcards ("foo*bar", "?", "[...]") are matched as globs.
+// Unlike path.Match, "/" and "\" are ordinary characters. Module forms
+// such as ":mod:ext4" are not supported.
+func MatchFilterPattern(pattern, name string) (bool, error) {
+	if strings.Contains(pattern, ":") {
+		return false, fmt.Errorf("%w: module and command patterns cannot be matched: %q", ErrInvalidValue, pattern)
+	}
+
+	inner := strings.TrimSuffix(strings.TrimPrefix(pattern, "*"), "*")
+	if !strings.ContainsAny(inner, "*?[") {
+		// The simple forms the kernel matches without a glob.
+		front, end := strings.HasSuffix(pattern, "*"), strings.HasPrefix(pattern, "*")
+		switch {
+		case pattern == "*":
+			return true, nil
+		case front && end:
+			return strings.Contains(name, inner), nil
+		case front:
+			return strings.HasPrefix(name, inner), nil
+		case end:
+			return strings.HasSuffix(name, inner), nil
+		}
+		return name == pattern, nil
+	}
+
+	return globMatch(pattern, name), nil
+}
+
+// globMatch matches name against pattern like the kernel's glob_match:
+// "*" matches any string, "?" any character, and "[...]" a character class
+// of single characters and ranges, negated by a leading "!". A "]" right
+// after the opening "[" is part of the class, and a "[" with no closing
+// "]" matches itself.
+func globMatch(pattern, name string) bool {
+	var (
+		p, n         int
+		starP, starN = -1, 0
+	)
+	for n < len(name) || p < len(pattern) {
+		if p < len(pattern) {
+			switch c := pattern[p]; c {
+			case '*':
+				// Try an empty match first, and remember where to retry
+				// with one more character consumed.
+				starP, starN = p, n
+				p++
+				continue
+			case '?':
+				if n < len(name) {
+					p++
+					n++
+					continue
+				}
+			case '[':
+				if n < len(name) {
+					if end, ok := matchClass(pattern[p+1:], name[n]); end >= 0 {
+						if ok {
+							p += end + 2
+							n++
+							continue
+						}
+						break
+					}
+				}
+				if n < len(name) && name[n] == c {
+					p++
+					n++
+					continue
+				}
+			default:
+				if n < len(name) && name[n] == c {
+					p++
+					n++
+					continue
+				}
+			}
+		}
+		if starP < 0 || starN >= len(name) {
+			return false
+		}
+		starN++
+		p, n = starP+1, starN
+	}
+	return true
+}
+
+// matchClass matches c against the character class at the start of class,
+// just after its "[". It returns the index of the closing "]", or -1 if the
+// class is not terminated.
+func matchClass(class string, c byte) (int, bool) {
+	i := 0
+	inverted := i < len(class) && class[i] == '!'
+	if inverted {
+		i++
+	}
+
+	var match bool
+	// The first span may begin with "]".
+	for first := true; i < len(class) && (first || class[i] != ']'); first = false {
+		lo, hi := class[i], class[i]
+		if i+2 < len(class) && class[i+1] == '-' && class[i+2] != ']' {
+			hi = class[i+2]
+			i += 2
+		}
+		i++
+		if lo <= c && c <= hi {
+			match = true
+		}
+	}
+	if i >= len(class) {
+		return -1, false
+	}
+	return i, match != inverted
+}
+
+// CountFilterFunctions returns the number of available filter functions
+// matching pattern, as matched by MatchFilterPattern. The list is streamed
+// rather than loaded, so this is cheap enough to check a pattern before
+// setting a filter.
+func (i *Instance) CountFilterFunctions(pattern string) (int, error) {
+	// Check the pattern once so a bad pattern is not reported per function.
+	if _, err := MatchFilterPattern(pattern, ""); err != nil {
+		return 0, err
+	}
+
+	var n int
+	err := i.walkFilterFunctions(func(name string) {
+		if ok, _ := MatchFilterPattern(pattern, name); ok {
+			n++
+		}
+	})
+	return n, err
+}
+
+// walkFilterFunctions calls fn for each function in
+// available_filter_functions.
+func (i *Instance) walkFilterFunctions(fn func(name string)) error {
+	f, err := i.top().open("available_filter_functions")
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		fn(fields[0])
+	}
+	return scanner.Err()
+}
+
+// readFilterFile reads a function list file such as set_ftrace_filter,
+// skipping the comment lines the kernel emits (e.g.
+// "#### all functions enabled ####").
+func (i *Instance) readFilterFile(name string) ([]string, error) {
+	lines, err := i.readLines(name)
+	if err != nil {
+		return nil, err
+	}
+
+	out := []string{}
+	for _, line := range lines {
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		out = append(out, line)
+	}
+	return out, nil
+}
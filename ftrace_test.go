@@ -0,0 +1,75 @@
+package tracefs
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMatchFilterPattern(t *testing.T) {
+	tests := []struct {
+		pattern, name string
+		want          bool
+	}{
+		{"*", "schedule", true},
+		{"schedule", "schedule", true},
+		{"schedule", "schedule_idle", false},
+		{"sched*", "schedule_idle", true},
+		{"*idle", "schedule_idle", true},
+		{"*ule_i*", "schedule_idle", true},
+		{"*ule_i*", "schedule", false},
+		{"tcp_*_rcv", "tcp_v4_rcv", true},
+		{"tcp_*_rcv", "tcp_v4_rcv_x", false},
+		{"*a*b*c", "xaxbxbxc", true},
+		{"*a*b*c", "xaxbxbx", false},
+		{"vfs_rea?", "vfs_read", true},
+		{"vfs_rea?", "vfs_rea", false},
+		{"sys_[rw]*", "sys_write", true},
+		{"sys_[rw]*", "sys_open", false},
+		{"sys_[!rw]*", "sys_open", true},
+		{"sys_[!rw]*", "sys_read", false},
+		{"irq[0-9]", "irq7", true},
+		{"irq[0-9]", "irqa", false},
+		{"x[]]*", "x]y", true},
+		{"x[a-]*", "x-", true},
+		// An unterminated class matches "[" literally.
+		{"x[a*", "x[abc", true},
+		{"x[a*", "xa", false},
+		// "/" and "\" are ordinary characters, unlike in path.Match.
+		{"*/*", "a/b", true},
+		{"a?b*", "a/b", true},
+		{"a\\*", "a\\b", true},
+		{"a\\?", "a?", false},
+	}
+	for _, tt := range tests {
+		got, err := MatchFilterPattern(tt.pattern, tt.name)
+		if err != nil {
+			t.Errorf("MatchFilterPattern(%q, %q): %v", tt.pattern, tt.name, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("MatchFilterPattern(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMatchFilterPatternModule(t *testing.T) {
+	if _, err := MatchFilterPattern(":mod:ext4", "ext4_sync_fs"); !errors.Is(err, ErrInvalidValue) {
+		t.Errorf("MatchFilterPattern with a module pattern = %v, want ErrInvalidValue", err)
+	}
+}
+
+func TestCountFilterFunctions(t *testing.T) {
+	fsys, _, child := newTestChild(t)
+	fsys.addFile("/t/available_filter_functions", "schedule\nschedule_idle\nvfs_read\next4_sync_fs [ext4]\n")
+
+	n, err := child.CountFilterFunctions("sched*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("CountFilterFunctions(sched*) = %d, want 2", n)
+	}
+	if n, _ := child.CountFilterFunctions("ext4_*"); n != 1 {
+		t.Errorf("CountFilterFunctions(ext4_*) = %d, want 1", n)
+	}
+}
@@ -0,0 +1,91 @@
+package tracefs
+
+import (
+	"fmt"
+	"strings"
+)
+
+var (
+	maxGraphDepthPath = "max_graph_depth"
+	graphFunctionPath = "set_graph_function"
+	graphNotracePath  = "set_graph_notrace"
+)
+
+// MaxGraphDepth returns the function_graph tracer's maximum call depth. 0
+// means unlimited.
+func (i *Instance) MaxGraphDepth() (int, error) {
+	return i.readInt(maxGraphDepthPath)
+}
+
+// SetMaxGraphDepth limits the call depth recorded by the function_graph
+// tracer. 0 means unlimited. It only has an effect while function_graph is
+// the current tracer.
+func (i *Instance) SetMaxGraphDepth(d int) error {
+	if d < 0 {
+		return fmt.Errorf("%w: graph depth must not be negative: %d", ErrInvalidValue, d)
+	}
+	return i.writeInt(maxGraphDepthPath, d)
+}
+
+// SetGraphFunctions limits the function_graph tracer to call graphs starting
+// at functions matching patterns, replacing the existing list. Patterns use
+// the same syntax as SetFtraceFilter. An empty list clears it.
+func (i *Instance) SetGraphFunctions(patterns []string) error {
+	return i.replaceFile(graphFunctionPath, []byte(strings.Join(patterns, "\n")))
+}
+
+// GraphFunctions returns the functions that start a call graph.
+func (i *Instance) GraphFunctions() ([]string, error) {
+	return i.readFilterFile(graphFunctionPath)
+}
+
+// SetGraphNotrace excludes functions matching patterns, and everything they
+// call, from the function_graph tracer, replacing the existing list.
+func (i *Instance) SetGraphNotrace(patterns []string) error {
+	return i.replaceFile(graphNotracePath, []byte(strings.Join(patterns, "\n")))
+}
+
+// GraphNotrace returns the functions excluded from the function_graph
+// tracer.
+func (i *Instance) GraphNotrace() ([]string, error) {
+	return i.readFilterFile(graphNotracePath)
+}
+
+// GraphOption is a function_graph tracer option. The options only exist
+// under options/ while function_graph is the current tracer; otherwise
+// setting one fails with an UnknownOptionError.
+type GraphOption string
+
+const (
+	// GraphIRQs traces functions called in interrupt context.
+	GraphIRQs GraphOption = "funcgraph-irqs"
+	// GraphProc shows the command and pid of each call.
+	GraphProc GraphOption = "funcgraph-proc"
+	// GraphAbsTime shows the absolute timestamp of each call.
+	GraphAbsTime GraphOption = "funcgraph-abstime"
+	// GraphDuration shows the duration at the end of each function.
+	GraphDuration GraphOption = "funcgraph-duration"
+	// GraphOverhead marks calls whose duration exceeds a threshold.
+	GraphOverhead GraphOption = "funcgraph-overhead"
+	// GraphCPU shows the CPU number of each call.
+	GraphCPU GraphOption = "funcgraph-cpu"
+	// GraphTail prints the function name on closing braces.
+	GraphTail GraphOption = "funcgraph-tail"
+	// GraphRetval shows function return values.
+	GraphRetval GraphOption = "funcgraph-retval"
+	// GraphSleepTime includes time spent sleeping in durations.
+	GraphSleepTime GraphOption = "sleep-time"
+	// GraphTime includes time spent in nested calls in function profile
+	// times.
+	GraphTime GraphOption = "graph-time"
+)
+
+// SetGraphOption turns a function_graph tracer option on or off.
+func (i *Instance) SetGraphOption(opt GraphOption, on bool) error {
+	return i.SetOption(string(opt), on)
+}
+
+// GraphOptionEnabled returns the state of a function_graph tracer option.
+func (i *Instance) GraphOptionEnabled(opt GraphOption) (bool, error) {
+	return i.Option(string(opt))
+}
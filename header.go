@@ -0,0 +1,170 @@
+package tracefs
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// PageHeader is the ring buffer page header layout from
+// events/header_page. Offsets and sizes are in bytes.
+type PageHeader struct {
+	TimestampOffset int
+	TimestampSize   int
+	CommitOffset    int
+	CommitSize      int
+	// OverwriteOffset is the byte of the commit field holding the
+	// overwrite flag.
+	OverwriteOffset int
+	DataOffset      int
+	// DataSize is the space available for events in a page.
+	DataSize int
+}
+
+// HeaderPage returns the ring buffer page header layout.
+func (i *Instance) HeaderPage() (*PageHeader, error) {
+	data, err := i.readFile(filepath.Join(eventDir(Event{}), "header_page"))
+	if err != nil {
+		return nil, err
+	}
+	return parseHeaderPage(data)
+}
+
+func parseHeaderPage(data []byte) (*PageHeader, error) {
+	f, err := parseEventFormat(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		h     PageHeader
+		found = map[string]bool{}
+	)
+	for _, field := range f.Fields {
+		found[field.Name] = true
+		switch field.Name {
+		case "timestamp":
+			h.TimestampOffset, h.TimestampSize = field.Offset, field.Size
+		case "commit":
+			h.CommitOffset, h.CommitSize = field.Offset, field.Size
+		case "overwrite":
+			h.OverwriteOffset = field.Offset
+		case "data":
+			h.DataOffset, h.DataSize = field.Offset, field.Size
+		}
+	}
+	for _, name := range []string{"timestamp", "commit", "data"} {
+		if !found[name] {
+			return nil, fmt.Errorf("header_page has no %s field", name)
+		}
+	}
+	return &h, nil
+}
+
+// layout converts h to the layout used by RawDecoder.
+func (h *PageHeader) layout() pageLayout {
+	return pageLayout{
+		timestampOffset: h.TimestampOffset,
+		commitOffset:    h.CommitOffset,
+		commitSize:      h.CommitSize,
+		dataOffset:      h.DataOffset,
+		pageSize:        h.DataOffset + h.DataSize,
+	}
+}
+
+// EventHeader is the compressed ring buffer event header layout from
+// events/header_event.
+type EventHeader struct {
+	TypeLenBits   int
+	TimeDeltaBits int
+	ArrayBits     int
+	// The type_len values of the special records.
+	PaddingType    int
+	TimeExtendType int
+	TimeStampType  int
+	// DataMaxTypeLen is the largest type_len that encodes a data length.
+	DataMaxTypeLen int
+}
+
+// HeaderEvent returns the ring buffer event header layout.
+func (i *Instance) HeaderEvent() (*EventHeader, error) {
+	data, err := i.readFile(filepath.Join(eventDir(Event{}), "header_event"))
+	if err != nil {
+		return nil, err
+	}
+	return parseHeaderEvent(data)
+}
+
+// parseHeaderEvent parses header_event, which looks like:
+//
+//	# compressed entry header
+//		type_len    :    5 bits
+//		time_delta  :   27 bits
+//		array       :   32 bits
+//
+//		padding     : type == 29
+//		time_extend : type == 30
+//		time_stamp : type == 31
+//		data max type_len  == 28
+func parseHeaderEvent(data []byte) (*EventHeader, error) {
+	var h EventHeader
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "data max type_len") {
+			fields := strings.Fields(line)
+			n, err := strconv.Atoi(fields[len(fields)-1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid header_event line %q: %w", line, err)
+			}
+			h.DataMaxTypeLen = n
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(value)
+		if len(fields) == 0 {
+			continue
+		}
+		n, err := strconv.Atoi(fields[len(fields)-1])
+		if err != nil && len(fields) >= 2 {
+			// "5 bits"
+			n, err = strconv.Atoi(fields[0])
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid header_event line %q: %w", line, err)
+		}
+
+		switch strings.TrimSpace(key) {
+		case "type_len":
+			h.TypeLenBits = n
+		case "time_delta":
+			h.TimeDeltaBits = n
+		case "array":
+			h.ArrayBits = n
+		case "padding":
+			h.PaddingType = n
+		case "time_extend":
+			h.TimeExtendType = n
+		case "time_stamp":
+			h.TimeStampType = n
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if h.TypeLenBits == 0 || h.TimeDeltaBits == 0 {
+		return nil, fmt.Errorf("header_event is missing the type_len or time_delta layout")
+	}
+	return &h, nil
+}
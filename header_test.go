@@ -0,0 +1,67 @@
+package tracefs
+
+import "testing"
+
+const sampleHeaderPage = `	field: u64 timestamp;	offset:0;	size:8;	signed:0;
+	field: local_t commit;	offset:8;	size:8;	signed:1;
+	field: int overwrite;	offset:8;	size:1;	signed:1;
+	field: char data;	offset:16;	size:4080;	signed:1;
+`
+
+func TestParseHeaderPage(t *testing.T) {
+	got, err := parseHeaderPage([]byte(sampleHeaderPage))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := PageHeader{
+		TimestampOffset: 0,
+		TimestampSize:   8,
+		CommitOffset:    8,
+		CommitSize:      8,
+		OverwriteOffset: 8,
+		DataOffset:      16,
+		DataSize:        4080,
+	}
+	if *got != want {
+		t.Errorf("parseHeaderPage = %+v, want %+v", *got, want)
+	}
+	if l := got.layout(); l.pageSize != 4096 || l.headerLen() != 16 {
+		t.Errorf("layout page size %d, header %d, want 4096 and 16", l.pageSize, l.headerLen())
+	}
+}
+
+func TestParseHeaderPageMissingField(t *testing.T) {
+	const input = "\tfield: u64 timestamp;\toffset:0;\tsize:8;\tsigned:0;\n"
+	if _, err := parseHeaderPage([]byte(input)); err == nil {
+		t.Error("parseHeaderPage accepted a header without commit and data")
+	}
+}
+
+func TestParseHeaderEvent(t *testing.T) {
+	const input = `# compressed entry header
+	type_len    :    5 bits
+	time_delta  :   27 bits
+	array       :   32 bits
+
+	padding     : type == 29
+	time_extend : type == 30
+	time_stamp : type == 31
+	data max type_len  == 28
+`
+	got, err := parseHeaderEvent([]byte(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := EventHeader{
+		TypeLenBits:    5,
+		TimeDeltaBits:  27,
+		ArrayBits:      32,
+		PaddingType:    29,
+		TimeExtendType: 30,
+		TimeStampType:  31,
+		DataMaxTypeLen: 28,
+	}
+	if *got != want {
+		t.Errorf("parseHeaderEvent = %+v, want %+v", *got, want)
+	}
+}
@@ -0,0 +1,260 @@
+package tracefs
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// HistTrigger builds a hist trigger specification. The zero value is not
+// valid; at least one key must be set.
+//
+//	h := HistTrigger{}.Keys("common_pid").Values("bytes_req").Sort("bytes_req.descending")
+type HistTrigger struct {
+	keys   []string
+	values []string
+	sort   []string
+	size   int
+	name   string
+	filter string
+}
+
+// Keys sets the fields to group by. Modifiers may be appended, e.g.
+// "call_site.sym".
+func (h HistTrigger) Keys(keys ...string) HistTrigger {
+	h.keys = append([]string(nil), keys...)
+	return h
+}
+
+// Values sets the fields to sum. hitcount is always included.
+func (h HistTrigger) Values(values ...string) HistTrigger {
+	h.values = append([]string(nil), values...)
+	return h
+}
+
+// Sort sets the sort keys, e.g. "hitcount" or "bytes_req.descending".
+func (h HistTrigger) Sort(keys ...string) HistTrigger {
+	h.sort = append([]string(nil), keys...)
+	return h
+}
+
+// Size sets the number of histogram entries. The kernel default is 2048.
+func (h HistTrigger) Size(n int) HistTrigger {
+	h.size = n
+	return h
+}
+
+// Name sets the name of the histogram so it can be shared between events.
+func (h HistTrigger) Name(name string) HistTrigger {
+	h.name = name
+	return h
+}
+
+// Filter only adds events matching expr to the histogram.
+func (h HistTrigger) Filter(expr string) HistTrigger {
+	h.filter = expr
+	return h
+}
+
+// String returns the trigger specification, e.g.
+// "hist:keys=common_pid:vals=bytes_req:sort=bytes_req:size=4096".
+func (h HistTrigger) String() string {
+	var b strings.Builder
+	b.WriteString("hist:keys=")
+	b.WriteString(strings.Join(h.keys, ","))
+	if len(h.values) > 0 {
+		b.WriteString(":vals=" + strings.Join(h.values, ","))
+	}
+	if len(h.sort) > 0 {
+		b.WriteString(":sort=" + strings.Join(h.sort, ","))
+	}
+	if h.size > 0 {
+		fmt.Fprintf(&b, ":size=%d", h.size)
+	}
+	if h.name != "" {
+		b.WriteString(":name=" + h.name)
+	}
+	if h.filter != "" {
+		b.WriteString(" if " + h.filter)
+	}
+	return b.String()
+}
+
+// AddHistTrigger attaches the hist trigger h to e.
+func (i *Instance) AddHistTrigger(e Event, h HistTrigger) error {
+	if len(h.keys) == 0 {
+		return fmt.Errorf("%w: hist trigger requires at least one key", ErrInvalidValue)
+	}
+	trigger := h.String()
+	return i.annotateErr(i.AddTrigger(e, trigger), trigger)
+}
+
+// RemoveHistTrigger removes the hist trigger h from e.
+func (i *Instance) RemoveHistTrigger(e Event, h HistTrigger) error {
+	return i.RemoveTrigger(e, h.String())
+}
+
+// Histogram is the parsed output of an event's hist file for a single
+// trigger.
+type Histogram struct {
+	// Trigger is the trigger spec reported in the hist header.
+	Trigger string
+	Buckets []HistBucket
+	Hits    uint64
+	Entries uint64
+	Dropped uint64
+}
+
+// HistBucket is a single histogram entry.
+type HistBucket struct {
+	// Keys are the key fields in trigger order.
+	Keys []HistKey
+	// Values holds hitcount and each summed value.
+	Values map[string]uint64
+}
+
+// HistKey is a key field of a HistBucket. Value is left as printed by the
+// kernel since keys may be symbols, strings or numbers.
+type HistKey struct {
+	Name  string
+	Value string
+}
+
+// ReadHist returns the histogram for e. If e has several hist triggers,
+// the first is returned; use ReadHists for all of them.
+func (i *Instance) ReadHist(e Event) (*Histogram, error) {
+	hists, err := i.ReadHists(e)
+	if err != nil {
+		return nil, err
+	}
+	if len(hists) == 0 {
+		return nil, fmt.Errorf("no hist trigger on %s", e)
+	}
+	return hists[0], nil
+}
+
+// ReadHists returns a histogram for each hist trigger on e.
+func (i *Instance) ReadHists(e Event) ([]*Histogram, error) {
+	data, err := i.readFile(filepath.Join(eventDir(e), "hist"))
+	if err != nil {
+		return nil, err
+	}
+	return parseHist(string(data))
+}
+
+var (
+	histKeyRe   = regexp.MustCompile(`(?:^|,)\s*([A-Za-z_][\w.]*):\s*`)
+	histValueRe = regexp.MustCompile(`([A-Za-z_][\w.]*):\s*(\d+)`)
+)
+
+// parseHist parses hist output:
+//
+//	# event histogram
+//	#
+//	# trigger info: hist:keys=common_pid:vals=hitcount:sort=hitcount:size=2048 [active]
+//	#
+//
+//	{ common_pid:       1234 } hitcount:          5  bytes_req:  100
+//	{ stacktrace:
+//	         kmem_cache_alloc+0x1b3/0x240
+//	         getname_flags+0x4f/0x1e0
+//	} hitcount:          3
+//
+//	Totals:
+//	    Hits: 5
+//	    Entries: 1
+//	    Dropped: 0
+func parseHist(s string) ([]*Histogram, error) {
+	var (
+		out []*Histogram
+		cur *Histogram
+		// pending holds the lines of a key that spans several lines, such
+		// as a stacktrace key, until its closing "}".
+		pending []string
+	)
+
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimSpace(line)
+
+		if pending != nil {
+			if !strings.HasPrefix(line, "}") {
+				if line != "" {
+					pending = append(pending, line)
+				}
+				continue
+			}
+			cur.Buckets = append(cur.Buckets, HistBucket{
+				Keys:   parseHistKeys(strings.Join(pending, "\n")),
+				Values: parseHistValues(line[1:]),
+			})
+			pending = nil
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, "# trigger info:"):
+			trigger := strings.TrimSpace(strings.TrimPrefix(line, "# trigger info:"))
+			trigger = strings.TrimSpace(strings.TrimSuffix(trigger, "[active]"))
+			cur = &Histogram{Trigger: trigger}
+			out = append(out, cur)
+		case strings.HasPrefix(line, "{"):
+			if cur == nil {
+				cur = &Histogram{}
+				out = append(out, cur)
+			}
+			end := strings.LastIndex(line, "}")
+			if end < 0 {
+				pending = []string{strings.TrimSpace(line[1:])}
+				continue
+			}
+			cur.Buckets = append(cur.Buckets, HistBucket{
+				Keys:   parseHistKeys(line[1:end]),
+				Values: parseHistValues(line[end+1:]),
+			})
+		case cur != nil && strings.HasPrefix(line, "Hits:"):
+			cur.Hits, _ = strconv.ParseUint(strings.TrimSpace(strings.TrimPrefix(line, "Hits:")), 10, 64)
+		case cur != nil && strings.HasPrefix(line, "Entries:"):
+			cur.Entries, _ = strconv.ParseUint(strings.TrimSpace(strings.TrimPrefix(line, "Entries:")), 10, 64)
+		case cur != nil && strings.HasPrefix(line, "Dropped:"):
+			cur.Dropped, _ = strconv.ParseUint(strings.TrimSpace(strings.TrimPrefix(line, "Dropped:")), 10, 64)
+		}
+	}
+	if pending != nil {
+		return nil, fmt.Errorf("unterminated hist key %q", strings.Join(pending, " "))
+	}
+
+	return out, nil
+}
+
+// parseHistKeys parses "common_pid: 1234, comm: bash" into its fields. For
+// multi-line keys such as stacktrace, the value keeps one frame per line.
+func parseHistKeys(s string) []HistKey {
+	var keys []HistKey
+	matches := histKeyRe.FindAllStringSubmatchIndex(s, -1)
+	for n, m := range matches {
+		end := len(s)
+		if n+1 < len(matches) {
+			end = matches[n+1][0]
+		}
+		keys = append(keys, HistKey{
+			Name:  s[m[2]:m[3]],
+			Value: strings.TrimSpace(s[m[1]:end]),
+		})
+	}
+	return keys
+}
+
+// parseHistValues parses "hitcount: 5  bytes_req: 100".
+func parseHistValues(s string) map[string]uint64 {
+	values := make(map[string]uint64)
+	for _, m := range histValueRe.FindAllStringSubmatch(s, -1) {
+		v, err := strconv.ParseUint(m[2], 10, 64)
+		if err != nil {
+			continue
+		}
+		values[m[1]] = v
+	}
+	return values
+}
@@ -0,0 +1,89 @@
+package tracefs
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseHist(t *testing.T) {
+	const input = `# event histogram
+#
+# trigger info: hist:keys=common_pid,comm:vals=hitcount,bytes_req:sort=hitcount:size=2048 [active]
+#
+
+{ common_pid:       1234, comm: bash            } hitcount:          5  bytes_req:        100
+{ common_pid:          1, comm: systemd         } hitcount:          2  bytes_req:         64
+
+Totals:
+    Hits: 7
+    Entries: 2
+    Dropped: 0
+
+# event histogram
+#
+# trigger info: hist:keys=stacktrace:vals=hitcount:sort=hitcount:size=2048 [active]
+#
+
+{ stacktrace:
+         kmem_cache_alloc+0x1b3/0x240
+         getname_flags+0x4f/0x1e0
+} hitcount:          3
+
+Totals:
+    Hits: 3
+    Entries: 1
+    Dropped: 1
+`
+
+	want := []*Histogram{
+		{
+			Trigger: "hist:keys=common_pid,comm:vals=hitcount,bytes_req:sort=hitcount:size=2048",
+			Buckets: []HistBucket{
+				{
+					Keys:   []HistKey{{Name: "common_pid", Value: "1234"}, {Name: "comm", Value: "bash"}},
+					Values: map[string]uint64{"hitcount": 5, "bytes_req": 100},
+				},
+				{
+					Keys:   []HistKey{{Name: "common_pid", Value: "1"}, {Name: "comm", Value: "systemd"}},
+					Values: map[string]uint64{"hitcount": 2, "bytes_req": 64},
+				},
+			},
+			Hits:    7,
+			Entries: 2,
+		},
+		{
+			Trigger: "hist:keys=stacktrace:vals=hitcount:sort=hitcount:size=2048",
+			Buckets: []HistBucket{
+				{
+					Keys:   []HistKey{{Name: "stacktrace", Value: "kmem_cache_alloc+0x1b3/0x240\ngetname_flags+0x4f/0x1e0"}},
+					Values: map[string]uint64{"hitcount": 3},
+				},
+			},
+			Hits:    3,
+			Entries: 1,
+			Dropped: 1,
+		},
+	}
+
+	got, err := parseHist(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseHist:\n got %+v\nwant %+v", got, want)
+	}
+}
+
+func TestParseHistUnterminatedKey(t *testing.T) {
+	if _, err := parseHist("{ stacktrace:\n  do_sys_open+0x10/0x20\n"); err == nil {
+		t.Error("parseHist accepted a key without a closing brace")
+	}
+}
+
+func TestHistTriggerString(t *testing.T) {
+	h := HistTrigger{}.Keys("common_pid").Values("bytes_req").Sort("bytes_req.descending").Size(4096).Filter("bytes_req > 64")
+	want := "hist:keys=common_pid:vals=bytes_req:sort=bytes_req.descending:size=4096 if bytes_req > 64"
+	if got := h.String(); got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
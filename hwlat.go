@@ -0,0 +1,124 @@
+package tracefs
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+var (
+	hwlatDir        = "hwlat_detector"
+	hwlatWidthPath  = filepath.Join(hwlatDir, "width")
+	hwlatWindowPath = filepath.Join(hwlatDir, "window")
+	hwlatModePath   = filepath.Join(hwlatDir, "mode")
+)
+
+// HWLatMode selects how the hwlat tracer moves its sampling thread between
+// CPUs.
+type HWLatMode string
+
+const (
+	// HWLatModeNone keeps the sampling thread on one CPU.
+	HWLatModeNone HWLatMode = "none"
+	// HWLatModeRoundRobin moves the sampling thread to the next CPU in
+	// tracing_cpumask after each window.
+	HWLatModeRoundRobin HWLatMode = "round-robin"
+	// HWLatModePerCPU runs a sampling thread on every CPU in
+	// tracing_cpumask.
+	HWLatModePerCPU HWLatMode = "per-cpu"
+)
+
+// The hwlat_detector directory only exists in the root instance.
+func (i *Instance) checkHWLat() error {
+	if !i.isRoot {
+		return fmt.Errorf("hwlat_detector is only available on the root instance: %w", ErrNotRoot)
+	}
+	return nil
+}
+
+// HWLatWidthUS returns the time in microseconds the hwlat tracer samples
+// with interrupts disabled in each window.
+func (i *Instance) HWLatWidthUS() (int, error) {
+	if err := i.checkHWLat(); err != nil {
+		return 0, err
+	}
+	return i.readInt(hwlatWidthPath)
+}
+
+// SetHWLatWidthUS sets the sampling width in microseconds. It must be less
+// than the window.
+func (i *Instance) SetHWLatWidthUS(us int) error {
+	if err := i.checkHWLat(); err != nil {
+		return err
+	}
+	if us <= 0 {
+		return fmt.Errorf("%w: hwlat width must be positive: %d", ErrInvalidValue, us)
+	}
+	return i.writeInt(hwlatWidthPath, us)
+}
+
+// HWLatWindowUS returns the period in microseconds of the hwlat tracer's
+// sampling cycle.
+func (i *Instance) HWLatWindowUS() (int, error) {
+	if err := i.checkHWLat(); err != nil {
+		return 0, err
+	}
+	return i.readInt(hwlatWindowPath)
+}
+
+// SetHWLatWindowUS sets the sampling window in microseconds. It must be
+// greater than the width.
+func (i *Instance) SetHWLatWindowUS(us int) error {
+	if err := i.checkHWLat(); err != nil {
+		return err
+	}
+	if us <= 0 {
+		return fmt.Errorf("%w: hwlat window must be positive: %d", ErrInvalidValue, us)
+	}
+	return i.writeInt(hwlatWindowPath, us)
+}
+
+// HWLatMode returns the selected hwlat thread mode.
+func (i *Instance) HWLatMode() (HWLatMode, error) {
+	if err := i.checkHWLat(); err != nil {
+		return "", err
+	}
+	data, err := i.readFile(hwlatModePath)
+	if err != nil {
+		return "", err
+	}
+
+	// The mode file uses the same "a [b] c" format as trace_clock.
+	_, current := parseTraceClock(data)
+	if current == "" {
+		return "", fmt.Errorf("no mode selected in %s: %s", hwlatModePath, data)
+	}
+	return HWLatMode(current), nil
+}
+
+// SetHWLatMode sets the hwlat thread mode. The kernel rejects changes while
+// the hwlat tracer is running.
+func (i *Instance) SetHWLatMode(mode HWLatMode) error {
+	if err := i.checkHWLat(); err != nil {
+		return err
+	}
+	return i.writeFile(hwlatModePath, []byte(mode))
+}
+
+// AvailableHWLatModes returns the hwlat thread modes supported by the
+// kernel.
+func (i *Instance) AvailableHWLatModes() ([]HWLatMode, error) {
+	if err := i.checkHWLat(); err != nil {
+		return nil, err
+	}
+	data, err := i.readFile(hwlatModePath)
+	if err != nil {
+		return nil, err
+	}
+
+	modes, _ := parseTraceClock(data)
+	out := make([]HWLatMode, len(modes))
+	for n, m := range modes {
+		out[n] = HWLatMode(m)
+	}
+	return out, nil
+}
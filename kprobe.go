@@ -0,0 +1,153 @@
+package tracefs
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+type KprobeEvent struct {
+	ReturnProbe bool
+	Group       string
+	Event       string
+	Symbol      string
+	Offset      uint64
+	FetchArgs   []FetchArg
+	// MaxActive is the number of concurrent calls a return probe can
+	// track (r<N>:). Calls beyond it are missed. 0 uses the kernel
+	// default, which depends on the number of CPUs.
+	MaxActive int
+}
+
+// maxKretprobeMaxActive is the largest maxactive the kernel accepts.
+const maxKretprobeMaxActive = 4096
+
+// Rule returns the kprobe_events definition for e. It does not check e;
+// call Validate before writing the rule through another path.
+func (e *KprobeEvent) Rule() string {
+	typ := "p"
+	if e.ReturnProbe {
+		typ = "r"
+		if e.MaxActive > 0 {
+			typ += strconv.Itoa(e.MaxActive)
+		}
+	}
+
+	var builder strings.Builder
+
+	builder.Write([]byte(typ))
+	if e.Group != "" && e.Event != "" {
+		fmt.Fprintf(&builder, ":%s/%s", e.Group, e.Event)
+	} else if e.Event != "" {
+		fmt.Fprintf(&builder, ":%s", e.Event)
+	}
+
+	fmt.Fprintf(&builder, " %s", e.Symbol)
+	if e.Offset != 0 {
+		fmt.Fprintf(&builder, "+%d", e.Offset)
+	}
+
+	for _, arg := range e.FetchArgs {
+		fmt.Fprintf(&builder, " %s", arg.String())
+	}
+
+	return builder.String()
+}
+
+// Validate checks e for mistakes the kernel would reject or silently
+// ignore, such as MaxActive on an entry probe, which Rule cannot express.
+func (e *KprobeEvent) Validate() error {
+	if e.Symbol == "" {
+		return fmt.Errorf("%w: kprobe symbol must not be empty", ErrInvalidValue)
+	}
+	if err := validateProbeName(e.Group, e.Event); err != nil {
+		return err
+	}
+	if err := validateFetchArgs(e.FetchArgs); err != nil {
+		return err
+	}
+	if !e.ReturnProbe && usesRetval(e.FetchArgs) {
+		return fmt.Errorf("%w: $retval can only be used on a return kprobe", ErrInvalidValue)
+	}
+	if e.MaxActive != 0 && !e.ReturnProbe {
+		return fmt.Errorf("%w: maxactive can only be used on a return kprobe", ErrInvalidValue)
+	}
+	if e.MaxActive < 0 || e.MaxActive > maxKretprobeMaxActive {
+		return fmt.Errorf("%w: maxactive %d must be between 0 and %d", ErrInvalidValue, e.MaxActive, maxKretprobeMaxActive)
+	}
+	return nil
+}
+
+// AddKprobeEvent appends e to kprobe_events. As with uprobes, on a child
+// instance the probe is defined in the root.
+func (i *Instance) AddKprobeEvent(e *KprobeEvent) error {
+	if err := e.Validate(); err != nil {
+		return err
+	}
+
+	root := i.top()
+	rule := e.Rule()
+	return root.annotateErr(root.appendLine(root.probeEventsFile("kprobe_events"), rule), rule)
+}
+
+func (i *Instance) KprobeEnablePath(e *KprobeEvent) string {
+	if e.Group != "" && e.Event != "" {
+		return filepath.Join(i.path, "events", e.Group, e.Event, "enable")
+	} else if e.Event != "" {
+		return filepath.Join(i.path, "events", "kprobes", e.Event, "enable")
+	}
+
+	return filepath.Join(i.path, "events", "kprobes", "enable")
+}
+
+func (i *Instance) EnableKprobe(e *KprobeEvent) error {
+	if err := validateProbeName(e.Group, e.Event); err != nil {
+		return err
+	}
+	return i.wrapErr(i.writePath(i.KprobeEnablePath(e), []byte("1"), false))
+}
+
+func (i *Instance) DisableKprobe(e *KprobeEvent) error {
+	if err := validateProbeName(e.Group, e.Event); err != nil {
+		return err
+	}
+	return i.wrapErr(i.writePath(i.KprobeEnablePath(e), []byte("0"), false))
+}
+
+// KprobeStat is a row of kprobe_profile.
+type KprobeStat struct {
+	Event string
+	// Hits is the number of times the probe fired.
+	Hits uint64
+	// Misses is the number of hits that were not recorded, e.g. because
+	// the probe was reentered.
+	Misses uint64
+}
+
+// KprobeProfile returns the hit and miss counts of each kprobe. The kernel
+// does not support resetting these counts; they start at zero when a probe
+// is created.
+func (i *Instance) KprobeProfile() ([]KprobeStat, error) {
+	lines, err := i.top().readLines("kprobe_profile")
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]KprobeStat, 0, len(lines))
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("invalid kprobe_profile line %q", line)
+		}
+		stat := KprobeStat{Event: fields[0]}
+		if stat.Hits, err = strconv.ParseUint(fields[1], 10, 64); err != nil {
+			return nil, fmt.Errorf("invalid kprobe_profile line %q: %w", line, err)
+		}
+		if stat.Misses, err = strconv.ParseUint(fields[2], 10, 64); err != nil {
+			return nil, fmt.Errorf("invalid kprobe_profile line %q: %w", line, err)
+		}
+		out = append(out, stat)
+	}
+	return out, nil
+}
@@ -0,0 +1,55 @@
+package tracefs
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestKprobeEventRule(t *testing.T) {
+	tests := []struct {
+		e    KprobeEvent
+		want string
+	}{
+		{KprobeEvent{Symbol: "do_sys_open"}, "p do_sys_open"},
+		{KprobeEvent{Group: "test", Event: "open", Symbol: "do_sys_open", Offset: 4}, "p:test/open do_sys_open+4"},
+		{KprobeEvent{ReturnProbe: true, Event: "ret", Symbol: "do_sys_open", MaxActive: 16,
+			FetchArgs: []FetchArg{Named("fd", WithType(FetchRetval(), ArgS32))}}, "r16:ret do_sys_open fd=$retval:s32"},
+	}
+	for _, tt := range tests {
+		if err := tt.e.Validate(); err != nil {
+			t.Errorf("Validate(%q): %v", tt.want, err)
+		}
+		if got := tt.e.Rule(); got != tt.want {
+			t.Errorf("Rule() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestKprobeEventValidate(t *testing.T) {
+	for _, e := range []KprobeEvent{
+		{},
+		{Symbol: "do_sys_open", MaxActive: 16},
+		{Symbol: "do_sys_open", ReturnProbe: true, MaxActive: -1},
+		{Symbol: "do_sys_open", ReturnProbe: true, MaxActive: maxKretprobeMaxActive + 1},
+		{Symbol: "do_sys_open", FetchArgs: []FetchArg{FetchRetval()}},
+		{Symbol: "do_sys_open", Event: "bad name"},
+	} {
+		e := e
+		if err := e.Validate(); !errors.Is(err, ErrInvalidValue) {
+			t.Errorf("Validate(%+v) = %v, want ErrInvalidValue", e, err)
+		}
+	}
+}
+
+func TestAddKprobeEventValidates(t *testing.T) {
+	fsys := newTracefs("/t", map[string]string{"/t/kprobe_events": ""})
+	i := RootInstance("/t", WithFS(fsys))
+
+	err := i.AddKprobeEvent(&KprobeEvent{Symbol: "do_sys_open", MaxActive: 16})
+	if !errors.Is(err, ErrInvalidValue) {
+		t.Errorf("AddKprobeEvent with MaxActive on an entry probe = %v, want ErrInvalidValue", err)
+	}
+	if got := fsys.written("/t/kprobe_events"); len(got) != 0 {
+		t.Errorf("kprobe_events was written %q", got)
+	}
+}
@@ -0,0 +1,35 @@
+package tracefs
+
+import "fmt"
+
+var (
+	maxLatencyPath    = "tracing_max_latency"
+	tracingThreshPath = "tracing_thresh"
+)
+
+// MaxLatencyUS returns the maximum latency in microseconds recorded by the
+// latency tracers (wakeup, irqsoff, etc.).
+func (i *Instance) MaxLatencyUS() (int, error) {
+	return i.readInt(maxLatencyPath)
+}
+
+// ResetMaxLatency resets the recorded maximum latency so the latency
+// tracers record the next maximum.
+func (i *Instance) ResetMaxLatency() error {
+	return i.writeInt(maxLatencyPath, 0)
+}
+
+// TracingThreshUS returns the latency threshold in microseconds. 0 means
+// the latency tracers record only new maximums.
+func (i *Instance) TracingThreshUS() (int, error) {
+	return i.readInt(tracingThreshPath)
+}
+
+// SetTracingThreshUS sets the latency threshold in microseconds. Latencies
+// above the threshold are recorded.
+func (i *Instance) SetTracingThreshUS(us int) error {
+	if us < 0 {
+		return fmt.Errorf("%w: threshold must not be negative: %d", ErrInvalidValue, us)
+	}
+	return i.writeInt(tracingThreshPath, us)
+}
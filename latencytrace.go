@@ -0,0 +1,112 @@
+package tracefs
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LatencyReport is the trace file of a latency tracer (irqsoff,
+// preemptoff, wakeup, etc.): the maximum latency section recorded and the
+// events within it.
+type LatencyReport struct {
+	Tracer string
+	// Version is the report format version, e.g. "v1.1.5".
+	Version string
+	// Kernel is the kernel release the trace was taken on.
+	Kernel  string
+	Latency time.Duration
+	// Entries is the number of events shown, out of Total recorded.
+	Entries int
+	Total   int
+	CPU     int
+	// Preemption is the preemption model summary, e.g.
+	// "M:preempt VP:0, KP:0, SP:0 HP:0 #P:4".
+	Preemption string
+	// Comm and PID identify the task that hit the latency.
+	Comm string
+	PID  int
+	// TaskInfo holds the task's uid, nice and scheduling details.
+	TaskInfo string
+	// StartedAt and EndedAt are the functions where the critical section
+	// began and ended. They are empty for the wakeup tracers.
+	StartedAt string
+	EndedAt   string
+	// Header is the full comment block, without the leading "#".
+	Header []string
+	Events []TraceEvent
+}
+
+var (
+	latencyTracerRe  = regexp.MustCompile(`^# tracer: (\S+)`)
+	latencyVersionRe = regexp.MustCompile(`^# \S+ latency trace (v\S+) on (\S+)`)
+	latencyHeaderRe  = regexp.MustCompile(`^# latency: (\d+) us, #(\d+)/(\d+), CPU#(\d+) \| \((.*)\)`)
+	latencyTaskRe    = regexp.MustCompile(`^#\s+\| task: (.*)-(\d+) \((.*)\)`)
+	latencyStartedRe = regexp.MustCompile(`=> started at:\s+(\S+)`)
+	latencyEndedRe   = regexp.MustCompile(`=> ended at:\s+(\S+)`)
+)
+
+// LatencyTrace reads and parses the trace file of a latency tracer. It
+// returns an error if the trace is not in the latency report format, for
+// example because the current tracer is not a latency tracer.
+func (i *Instance) LatencyTrace() (*LatencyReport, error) {
+	data, err := i.Trace()
+	if err != nil {
+		return nil, err
+	}
+	return parseLatencyTrace(data)
+}
+
+func parseLatencyTrace(data []byte) (*LatencyReport, error) {
+	var (
+		r        LatencyReport
+		haveHead bool
+	)
+
+	scanner := newLineScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "#") {
+			// Lines that do not parse, such as " => func" stack trace
+			// entries, are skipped.
+			if ev, err := ParseTraceLine(line); err == nil {
+				r.Events = append(r.Events, ev)
+			}
+			continue
+		}
+
+		r.Header = append(r.Header, strings.TrimPrefix(line, "#"))
+		if m := latencyTracerRe.FindStringSubmatch(line); m != nil {
+			r.Tracer = m[1]
+		} else if m := latencyVersionRe.FindStringSubmatch(line); m != nil {
+			r.Version, r.Kernel = m[1], m[2]
+		} else if m := latencyHeaderRe.FindStringSubmatch(line); m != nil {
+			us, _ := strconv.ParseInt(m[1], 10, 64)
+			r.Latency = time.Duration(us) * time.Microsecond
+			r.Entries, _ = strconv.Atoi(m[2])
+			r.Total, _ = strconv.Atoi(m[3])
+			r.CPU, _ = strconv.Atoi(m[4])
+			r.Preemption = m[5]
+			haveHead = true
+		} else if m := latencyTaskRe.FindStringSubmatch(line); m != nil {
+			r.Comm = m[1]
+			r.PID, _ = strconv.Atoi(m[2])
+			r.TaskInfo = m[3]
+		} else if m := latencyStartedRe.FindStringSubmatch(line); m != nil {
+			r.StartedAt = m[1]
+		} else if m := latencyEndedRe.FindStringSubmatch(line); m != nil {
+			r.EndedAt = m[1]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if !haveHead {
+		return nil, fmt.Errorf("trace is not a latency report (tracer %q)", r.Tracer)
+	}
+	return &r, nil
+}
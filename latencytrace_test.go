@@ -0,0 +1,86 @@
+package tracefs
+
+import (
+	"testing"
+	"time"
+)
+
+// irqsoffTrace is an irqsoff trace from Documentation/trace/ftrace.rst.
+const irqsoffTrace = `# tracer: irqsoff
+#
+# irqsoff latency trace v1.1.5 on 4.5.0-rc6+
+# --------------------------------------------------------------------
+# latency: 16 us, #4/4, CPU#0 | (M:preempt VP:0, KP:0, SP:0 HP:0 #P:4)
+#    -----------------
+#    | task: swapper/0-0 (uid:0 nice:0 policy:0 rt_prio:0)
+#    -----------------
+#  => started at: run_timer_softirq
+#  => ended at:   run_timer_softirq
+#
+#
+#                  _------=> CPU#
+#                 / _-----=> irqs-off
+#                | / _----=> need-resched
+#                || / _---=> hardirq/softirq
+#                ||| / _--=> preempt-depth
+#                |||| /     delay
+#  cmd     pid   ||||| time  |   caller
+#     \   /      |||||  \    |   /
+  <idle>-0       0d.s2    0us+: _raw_spin_lock_irq <-run_timer_softirq
+  <idle>-0       0dNs3   17us : _raw_spin_unlock_irq <-run_timer_softirq
+  <idle>-0       0dNs3   17us+: trace_hardirqs_on <-run_timer_softirq
+  <idle>-0       0dNs3   25us : <stack trace>
+ => _raw_spin_unlock_irq
+ => run_timer_softirq
+ => __do_softirq
+ => irq_exit
+`
+
+func TestLatencyTrace(t *testing.T) {
+	fsys := newTracefs("/t", map[string]string{"/t/trace": irqsoffTrace})
+	i := RootInstance("/t", WithFS(fsys))
+
+	r, err := i.LatencyTrace()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r.Tracer != "irqsoff" || r.Version != "v1.1.5" || r.Kernel != "4.5.0-rc6+" {
+		t.Errorf("tracer %q version %q kernel %q, want irqsoff v1.1.5 4.5.0-rc6+", r.Tracer, r.Version, r.Kernel)
+	}
+	if r.Latency != 16*time.Microsecond || r.Entries != 4 || r.Total != 4 || r.CPU != 0 {
+		t.Errorf("latency %v #%d/%d CPU#%d, want 16us #4/4 CPU#0", r.Latency, r.Entries, r.Total, r.CPU)
+	}
+	if r.Preemption != "M:preempt VP:0, KP:0, SP:0 HP:0 #P:4" {
+		t.Errorf("Preemption = %q", r.Preemption)
+	}
+	if r.Comm != "swapper/0" || r.PID != 0 || r.TaskInfo != "uid:0 nice:0 policy:0 rt_prio:0" {
+		t.Errorf("task %q-%d (%s), want swapper/0-0", r.Comm, r.PID, r.TaskInfo)
+	}
+	if r.StartedAt != "run_timer_softirq" || r.EndedAt != "run_timer_softirq" {
+		t.Errorf("started at %q ended at %q, want run_timer_softirq", r.StartedAt, r.EndedAt)
+	}
+	if len(r.Header) != 20 {
+		t.Errorf("Header has %d lines, want 20", len(r.Header))
+	}
+
+	// The stack trace lines after the events are skipped.
+	if len(r.Events) != 4 {
+		t.Fatalf("got %d events, want 4: %+v", len(r.Events), r.Events)
+	}
+	ev := r.Events[1]
+	if ev.Comm != "<idle>" || ev.CPU != 0 || ev.Flags != "dNs3" || ev.Timestamp != 17*time.Microsecond ||
+		ev.Function != "_raw_spin_unlock_irq" || ev.Rest != "<-run_timer_softirq" {
+		t.Errorf("event 1 = %+v", ev)
+	}
+}
+
+func TestLatencyTraceNotLatencyTracer(t *testing.T) {
+	fsys := newTracefs("/t", map[string]string{
+		"/t/trace": "# tracer: nop\n#\n# entries-in-buffer/entries-written: 0/0   #P:4\n",
+	})
+	i := RootInstance("/t", WithFS(fsys))
+	if _, err := i.LatencyTrace(); err == nil {
+		t.Error("LatencyTrace accepted a nop trace")
+	}
+}
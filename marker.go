@@ -0,0 +1,74 @@
+package tracefs
+
+import (
+	"fmt"
+	"path/filepath"
+	"unicode/utf8"
+)
+
+// markerMaxLen is the largest write older kernels accept on trace_marker
+// without truncating it. Kernels with buffer_subbuf_size_kb (6.8+) accept
+// markerMaxLenLarge.
+const (
+	markerMaxLen      = 1024
+	markerMaxLenLarge = 4096
+)
+
+// MarkerMaxLen returns the largest marker the kernel accepts in a single
+// write without truncating it. The kernel does not expose the limit
+// directly, so it is derived from the features of the running kernel and
+// errs on the small side.
+func (i *Instance) MarkerMaxLen() int {
+	if _, err := i.fsys().Stat(filepath.Join(i.path, "buffer_subbuf_size_kb")); err == nil {
+		return markerMaxLenLarge
+	}
+	return markerMaxLen
+}
+
+// WriteMarker writes s to trace_marker, annotating the trace buffer. Markers
+// longer than MarkerMaxLen are rejected rather than silently truncated by
+// the kernel; use WriteMarkerSplit to write them in pieces.
+func (i *Instance) WriteMarker(s string) error {
+	if limit := i.MarkerMaxLen(); len(s) > limit {
+		return fmt.Errorf("%w: marker length %d exceeds max %d", ErrInvalidValue, len(s), limit)
+	}
+	return i.writeFile("trace_marker", []byte(s))
+}
+
+// WriteMarkerSplit writes s to trace_marker as one or more markers of at
+// most MarkerMaxLen bytes. Splits fall on UTF-8 character boundaries.
+func (i *Instance) WriteMarkerSplit(s string) error {
+	limit := i.MarkerMaxLen()
+	for len(s) > limit {
+		n := limit
+		for n > 0 && !utf8.RuneStart(s[n]) {
+			n--
+		}
+		if n == 0 {
+			n = limit
+		}
+		if err := i.writeFile("trace_marker", []byte(s[:n])); err != nil {
+			return err
+		}
+		s = s[n:]
+	}
+	return i.writeFile("trace_marker", []byte(s))
+}
+
+// WriteMarkerf formats according to format and writes the result with
+// WriteMarker.
+func (i *Instance) WriteMarkerf(format string, args ...any) error {
+	return i.WriteMarker(fmt.Sprintf(format, args...))
+}
+
+// WriteMarkerRaw writes b to trace_marker_raw. The kernel requires the first
+// 4 bytes to be a tag id used to identify the payload.
+func (i *Instance) WriteMarkerRaw(b []byte) error {
+	if len(b) < 4 {
+		return fmt.Errorf("%w: raw marker must be at least 4 bytes", ErrInvalidValue)
+	}
+	if limit := i.MarkerMaxLen(); len(b) > limit {
+		return fmt.Errorf("%w: raw marker length %d exceeds max %d", ErrInvalidValue, len(b), limit)
+	}
+	return i.writeFile("trace_marker_raw", b)
+}
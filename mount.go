@@ -0,0 +1,78 @@
+package tracefs
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+var procMountsPath = "/proc/mounts"
+
+// FindTracefs returns the path where tracefs is mounted. If tracefs is not
+// mounted directly but debugfs is, the tracing directory under the debugfs
+// mount is returned (the layout used by older kernels).
+func FindTracefs() (string, error) {
+	f, err := os.Open(procMountsPath)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	var debugfs string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 {
+			continue
+		}
+		switch fields[2] {
+		case "tracefs":
+			return unescapeMountPath(fields[1]), nil
+		case "debugfs":
+			if debugfs == "" {
+				debugfs = filepath.Join(unescapeMountPath(fields[1]), "tracing")
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+
+	if debugfs != "" {
+		return debugfs, nil
+	}
+	return "", ErrNotMounted
+}
+
+// FindRootInstance returns the root instance at the path found by
+// FindTracefs.
+func FindRootInstance() (Instance, error) {
+	path, err := FindTracefs()
+	if err != nil {
+		return Instance{}, err
+	}
+	return RootInstance(path), nil
+}
+
+// unescapeMountPath decodes the octal escapes (e.g. \040 for space) used in
+// /proc/mounts.
+func unescapeMountPath(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+4 <= len(s) {
+			if v, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(v))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
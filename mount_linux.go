@@ -0,0 +1,22 @@
+package tracefs
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// Mount mounts tracefs at /sys/kernel/tracing if it is not already mounted.
+func Mount() error {
+	_, err := FindTracefs()
+	if err == nil {
+		return nil
+	} else if !errors.Is(err, ErrNotMounted) {
+		return err
+	}
+
+	if err := os.MkdirAll(rootPath, 0755); err != nil {
+		return err
+	}
+	return syscall.Mount("nodev", rootPath, "tracefs", 0, "")
+}
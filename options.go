@@ -0,0 +1,207 @@
+package tracefs
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+var (
+	optionsDir       = "options"
+	traceOptionsPath = "trace_options"
+)
+
+// Names of common trace options, for use with Option and SetOption. Tracer
+// specific options are only present while that tracer is current; see
+// GraphOption for the function_graph options.
+const (
+	OptionPrintParent    = "print-parent"
+	OptionSymOffset      = "sym-offset"
+	OptionSymAddr        = "sym-addr"
+	OptionVerbose        = "verbose"
+	OptionRaw            = "raw"
+	OptionHex            = "hex"
+	OptionBin            = "bin"
+	OptionBlock          = "block"
+	OptionMarkers        = "markers"
+	OptionOverwrite      = "overwrite"
+	OptionIRQInfo        = "irq-info"
+	OptionLatencyFormat  = "latency-format"
+	OptionRecordCmd      = "record-cmd"
+	OptionRecordTGID     = "record-tgid"
+	OptionEventFork      = "event-fork"
+	OptionFunctionFork   = "function-fork"
+	OptionFuncStackTrace = "func_stack_trace"
+	OptionStackTrace     = "stacktrace"
+	OptionUserStackTrace = "userstacktrace"
+	OptionDisableOnFree  = "disable_on_free"
+	OptionPauseOnTrace   = "pause-on-trace"
+)
+
+// UnknownOptionError is returned when an option does not exist in the
+// instance's options directory.
+type UnknownOptionError struct {
+	Name string
+}
+
+func (e *UnknownOptionError) Error() string {
+	return fmt.Sprintf("unknown trace option: %s", e.Name)
+}
+
+// Options returns the state of every option under options/. This includes
+// options specific to the current tracer. If the kernel has no options/
+// directory, the trace_options file is used instead.
+func (i *Instance) Options() (map[string]bool, error) {
+	entries, err := i.fsys().ReadDir(filepath.Join(i.path, optionsDir))
+	if errors.Is(err, os.ErrNotExist) {
+		return i.TraceOptionsFile()
+	} else if err != nil {
+		return nil, i.wrapErr(err)
+	}
+
+	out := make(map[string]bool, len(entries))
+	for _, e := range entries {
+		on, err := i.Option(e.Name())
+		if err != nil {
+			return nil, err
+		}
+		out[e.Name()] = on
+	}
+
+	return out, nil
+}
+
+// Option returns the state of the named option.
+func (i *Instance) Option(name string) (bool, error) {
+	data, err := i.OptionRaw(name)
+	if err != nil {
+		return false, err
+	}
+
+	switch data {
+	case "0":
+		return false, nil
+	case "1":
+		return true, nil
+	}
+
+	return false, fmt.Errorf("unknown value for option %s: %s", name, data)
+}
+
+// SetOption turns the named option on or off.
+func (i *Instance) SetOption(name string, on bool) error {
+	v := "0"
+	if on {
+		v = "1"
+	}
+	return i.SetOptionRaw(name, v)
+}
+
+// OptionRaw returns the contents of options/<name> without interpreting
+// them. Option is the common path for boolean options; OptionRaw is for
+// files that hold other values. Without an options directory the value
+// comes from trace_options, which only lists boolean options, as "0" or
+// "1".
+func (i *Instance) OptionRaw(name string) (string, error) {
+	if err := validateOptionName(name); err != nil {
+		return "", err
+	}
+
+	if !i.hasOptionsDir() {
+		opts, err := i.TraceOptionsFile()
+		if err != nil {
+			return "", err
+		}
+		on, ok := opts[name]
+		if !ok {
+			return "", &UnknownOptionError{Name: name}
+		}
+		if on {
+			return "1", nil
+		}
+		return "0", nil
+	}
+
+	data, err := i.readFile(filepath.Join(optionsDir, name))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", &UnknownOptionError{Name: name}
+	} else if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// SetOptionRaw writes value to options/<name> as is. Without an options
+// directory, value must be "0" or "1" and is written to trace_options.
+func (i *Instance) SetOptionRaw(name, value string) error {
+	if err := validateOptionName(name); err != nil {
+		return err
+	}
+
+	if !i.hasOptionsDir() {
+		switch value {
+		case "0":
+			return i.SetTraceOption(name, false)
+		case "1":
+			return i.SetTraceOption(name, true)
+		}
+		return fmt.Errorf("%w: trace_options only holds boolean options, got %q for %s", ErrInvalidValue, value, name)
+	}
+
+	p := filepath.Join(optionsDir, name)
+	if _, err := i.fsys().Stat(filepath.Join(i.path, p)); errors.Is(err, os.ErrNotExist) {
+		return &UnknownOptionError{Name: name}
+	}
+	return i.writeFile(p, []byte(value))
+}
+
+// validateOptionName checks that name is a single file name, so it cannot
+// escape the options directory.
+func validateOptionName(name string) error {
+	if name == "" || name == "." || name == ".." || strings.Contains(name, "/") {
+		return fmt.Errorf("%w: invalid option name %q", ErrInvalidValue, name)
+	}
+	return nil
+}
+
+func (i *Instance) hasOptionsDir() bool {
+	_, err := i.fsys().Stat(filepath.Join(i.path, optionsDir))
+	return err == nil
+}
+
+// TraceOptionsFile returns the options listed in the trace_options file.
+// Options prefixed with "no" are off.
+func (i *Instance) TraceOptionsFile() (map[string]bool, error) {
+	lines, err := i.readLines(traceOptionsPath)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(map[string]bool, len(lines))
+	for _, line := range lines {
+		if strings.HasPrefix(line, "no") {
+			out[strings.TrimPrefix(line, "no")] = false
+		} else {
+			out[line] = true
+		}
+	}
+	return out, nil
+}
+
+// SetTraceOption turns the named option on or off through the trace_options
+// file.
+func (i *Instance) SetTraceOption(name string, on bool) error {
+	v := name
+	if !on {
+		v = "no" + name
+	}
+
+	err := i.writeFile(traceOptionsPath, []byte(v))
+	if errors.Is(err, syscall.EINVAL) {
+		return &UnknownOptionError{Name: name}
+	}
+	return err
+}
@@ -0,0 +1,153 @@
+package tracefs
+
+import (
+	"errors"
+	"reflect"
+	"syscall"
+	"testing"
+)
+
+func TestOptionRaw(t *testing.T) {
+	fsys := newTracefs("/t", map[string]string{
+		"/t/options/raw":         "0\n",
+		"/t/options/funcgraph-x": "1\n",
+	})
+	i := RootInstance("/t", WithFS(fsys))
+
+	if v, err := i.OptionRaw(OptionRaw); err != nil || v != "0" {
+		t.Errorf("OptionRaw(raw) = %q, %v, want \"0\"", v, err)
+	}
+	if err := i.SetOptionRaw(OptionRaw, "1"); err != nil {
+		t.Fatal(err)
+	}
+	if got := fsys.written("/t/options/raw"); !reflect.DeepEqual(got, []string{"1"}) {
+		t.Errorf("options/raw writes = %q, want [\"1\"]", got)
+	}
+	if on, err := i.Option(OptionRaw); err != nil || !on {
+		t.Errorf("Option(raw) = %v, %v, want true", on, err)
+	}
+
+	var unknown *UnknownOptionError
+	if _, err := i.OptionRaw("no-such-option"); !errors.As(err, &unknown) {
+		t.Errorf("OptionRaw(no-such-option) = %v, want *UnknownOptionError", err)
+	}
+	if err := i.SetOptionRaw("no-such-option", "1"); !errors.As(err, &unknown) {
+		t.Errorf("SetOptionRaw(no-such-option) = %v, want *UnknownOptionError", err)
+	}
+
+	for _, name := range []string{"", ".", "..", "../current_tracer", "a/b"} {
+		if _, err := i.OptionRaw(name); !errors.Is(err, ErrInvalidValue) {
+			t.Errorf("OptionRaw(%q) = %v, want ErrInvalidValue", name, err)
+		}
+		if err := i.SetOptionRaw(name, "1"); !errors.Is(err, ErrInvalidValue) {
+			t.Errorf("SetOptionRaw(%q) = %v, want ErrInvalidValue", name, err)
+		}
+	}
+}
+
+func TestOptionRawTraceOptionsFallback(t *testing.T) {
+	fsys := newTracefs("/t", map[string]string{
+		"/t/trace_options": "print-parent\nnosym-offset\nnoraw\n",
+	})
+	i := RootInstance("/t", WithFS(fsys))
+
+	if v, err := i.OptionRaw(OptionPrintParent); err != nil || v != "1" {
+		t.Errorf("OptionRaw(print-parent) = %q, %v, want \"1\"", v, err)
+	}
+	if v, err := i.OptionRaw(OptionRaw); err != nil || v != "0" {
+		t.Errorf("OptionRaw(raw) = %q, %v, want \"0\"", v, err)
+	}
+
+	if err := i.SetOptionRaw(OptionRaw, "1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := i.SetOptionRaw(OptionSymOffset, "0"); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := fsys.written("/t/trace_options"), []string{"raw", "nosym-offset"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("trace_options writes = %q, want %q", got, want)
+	}
+	if err := i.SetOptionRaw(OptionRaw, "2"); !errors.Is(err, ErrInvalidValue) {
+		t.Errorf("SetOptionRaw(raw, 2) without options/ = %v, want ErrInvalidValue", err)
+	}
+}
+
+// traceOptionsFile is trace_options from a 6.8 kernel.
+const traceOptionsFile = `print-parent
+nosym-offset
+nosym-addr
+noverbose
+noraw
+nohex
+nobin
+noblock
+nofields
+trace_printk
+annotate
+nouserstacktrace
+nosym-userobj
+noprintk-msg-only
+context-info
+nolatency-format
+record-cmd
+norecord-tgid
+overwrite
+nodisable_on_free
+irq-info
+markers
+noevent-fork
+nopause-on-trace
+hash-ptr
+function-trace
+nofunction-fork
+nodisplay-graph
+nostacktrace
+`
+
+func TestTraceOptionsFallback(t *testing.T) {
+	fsys := newTracefs("/t", map[string]string{"/t/trace_options": traceOptionsFile})
+	i := RootInstance("/t", WithFS(fsys))
+
+	opts, err := i.Options()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(opts) != 29 {
+		t.Errorf("Options() has %d entries, want 29", len(opts))
+	}
+	for name, want := range map[string]bool{
+		OptionPrintParent: true,
+		OptionSymOffset:   false,
+		OptionRecordCmd:   true,
+		OptionRecordTGID:  false,
+		OptionEventFork:   false,
+		"function-trace":  true,
+	} {
+		if opts[name] != want {
+			t.Errorf("Options()[%q] = %v, want %v", name, opts[name], want)
+		}
+		if on, err := i.Option(name); err != nil || on != want {
+			t.Errorf("Option(%q) = %v, %v, want %v", name, on, err, want)
+		}
+	}
+
+	if err := i.SetOption(OptionVerbose, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := i.SetOption(OptionOverwrite, false); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := fsys.written("/t/trace_options"), []string{"verbose", "nooverwrite"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("trace_options writes = %q, want %q", got, want)
+	}
+
+	var unknown *UnknownOptionError
+	if _, err := i.Option("no-such-option"); !errors.As(err, &unknown) {
+		t.Errorf("Option(no-such-option) = %v, want *UnknownOptionError", err)
+	}
+	// The kernel rejects unknown names written to trace_options.
+	fsys.writeErrs["/t/trace_options"] = []error{syscall.EINVAL}
+	if err := i.SetOption("no-such-option", true); !errors.As(err, &unknown) {
+		t.Errorf("SetOption(no-such-option) = %v, want *UnknownOptionError", err)
+	}
+}
@@ -0,0 +1,116 @@
+package tracefs
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	ftracePIDPath       = "set_ftrace_pid"
+	eventPIDPath        = "set_event_pid"
+	eventNotracePIDPath = "set_event_notrace_pid"
+)
+
+// SetFtracePIDs limits the function tracers to pids, replacing the existing
+// list. Opening the file with truncation clears it, so an empty list traces
+// all pids.
+func (i *Instance) SetFtracePIDs(pids []int) error {
+	return i.replaceFile(ftracePIDPath, []byte(formatPIDs(pids)))
+}
+
+// AddFtracePID adds pid to the function tracer pid filter.
+func (i *Instance) AddFtracePID(pid int) error {
+	return i.appendLine(ftracePIDPath, strconv.Itoa(pid))
+}
+
+// FtracePIDs returns the function tracer pid filter. An empty list means all
+// pids are traced.
+func (i *Instance) FtracePIDs() ([]int, error) {
+	return i.readPIDs(ftracePIDPath)
+}
+
+// SetEventPIDs limits trace events (tracepoints) to pids, replacing the
+// existing list. This is separate from the function tracer pid filter. An
+// empty list traces all pids. Children forked by pids are not traced unless
+// event-fork is set; see SetEventPIDsFollowFork.
+func (i *Instance) SetEventPIDs(pids []int) error {
+	return i.replaceFile(eventPIDPath, []byte(formatPIDs(pids)))
+}
+
+// EventPIDs returns the trace event pid filter.
+func (i *Instance) EventPIDs() ([]int, error) {
+	return i.readPIDs(eventPIDPath)
+}
+
+// SetEventNotracePIDs excludes pids from generating trace events, replacing
+// the existing list.
+func (i *Instance) SetEventNotracePIDs(pids []int) error {
+	return i.replaceFile(eventNotracePIDPath, []byte(formatPIDs(pids)))
+}
+
+// EventNotracePIDs returns the pids excluded from trace events.
+func (i *Instance) EventNotracePIDs() ([]int, error) {
+	return i.readPIDs(eventNotracePIDPath)
+}
+
+// SetEventFork sets the event-fork option. When on, children of pids in
+// set_event_pid are added to the list when they fork, and removed when they
+// exit.
+func (i *Instance) SetEventFork(on bool) error {
+	return i.SetOption(OptionEventFork, on)
+}
+
+// SetFunctionFork sets the function-fork option, which does the same as
+// event-fork for set_ftrace_pid.
+func (i *Instance) SetFunctionFork(on bool) error {
+	return i.SetOption(OptionFunctionFork, on)
+}
+
+// SetEventPIDsFollowFork is SetEventPIDs with event-fork enabled first, so
+// processes the pids spawn are traced too.
+func (i *Instance) SetEventPIDsFollowFork(pids []int) error {
+	if err := i.SetEventFork(true); err != nil {
+		return err
+	}
+	return i.SetEventPIDs(pids)
+}
+
+// SetFtracePIDsFollowFork is SetFtracePIDs with function-fork enabled
+// first, so processes the pids spawn are traced too.
+func (i *Instance) SetFtracePIDsFollowFork(pids []int) error {
+	if err := i.SetFunctionFork(true); err != nil {
+		return err
+	}
+	return i.SetFtracePIDs(pids)
+}
+
+func formatPIDs(pids []int) string {
+	parts := make([]string, len(pids))
+	for n, pid := range pids {
+		parts[n] = strconv.Itoa(pid)
+	}
+	return strings.Join(parts, " ")
+}
+
+// readPIDs parses a whitespace separated pid list. The kernel reports
+// "no pid" when the list is empty.
+func (i *Instance) readPIDs(name string) ([]int, error) {
+	data, err := i.readFile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	pids := []int{}
+	if string(data) == "no pid" {
+		return pids, nil
+	}
+	for _, f := range strings.Fields(string(data)) {
+		pid, err := strconv.Atoi(f)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pid %q in %s: %w", f, name, err)
+		}
+		pids = append(pids, pid)
+	}
+	return pids, nil
+}
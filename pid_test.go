@@ -0,0 +1,42 @@
+package tracefs
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestReadPIDs(t *testing.T) {
+	tests := []struct {
+		data string
+		want []int
+	}{
+		// set_ftrace_pid with no pids.
+		{"no pid\n", []int{}},
+		// set_event_pid with no pids.
+		{"", []int{}},
+		{"1234\n", []int{1234}},
+		{"1\n42\n1234\n", []int{1, 42, 1234}},
+	}
+
+	for _, tt := range tests {
+		fsys := newTracefs("/t", map[string]string{"/t/set_ftrace_pid": tt.data})
+		i := RootInstance("/t", WithFS(fsys))
+
+		got, err := i.FtracePIDs()
+		if err != nil {
+			t.Errorf("FtracePIDs with %q: %v", tt.data, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("FtracePIDs with %q = %v, want %v", tt.data, got, tt.want)
+		}
+	}
+}
+
+func TestReadPIDsInvalid(t *testing.T) {
+	fsys := newTracefs("/t", map[string]string{"/t/set_event_pid": "12 abc\n"})
+	i := RootInstance("/t", WithFS(fsys))
+	if _, err := i.EventPIDs(); err == nil {
+		t.Error("EventPIDs accepted a non-numeric pid")
+	}
+}
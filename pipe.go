@@ -0,0 +1,234 @@
+package tracefs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// CPUTracePipe opens per_cpu/cpuN/trace_pipe, a consuming reader for a
+// single cpu's buffer. Using one reader per cpu avoids the contention of the
+// merged trace_pipe.
+func (i *Instance) CPUTracePipe(cpu int) (io.ReadCloser, error) {
+	dir, err := i.cpuDir(cpu)
+	if err != nil {
+		return nil, err
+	}
+	return i.open(filepath.Join(dir, "trace_pipe"))
+}
+
+// CPUTraceReader opens per_cpu/cpuN/trace, a non-destructive snapshot of a
+// single cpu's buffer.
+func (i *Instance) CPUTraceReader(cpu int) (io.ReadCloser, error) {
+	dir, err := i.cpuDir(cpu)
+	if err != nil {
+		return nil, err
+	}
+	return i.open(filepath.Join(dir, "trace"))
+}
+
+// TracePipeContext opens trace_pipe like TracePipe, but closes it when ctx
+// is done. A Read blocked waiting for events returns ctx.Err().
+func (i *Instance) TracePipeContext(ctx context.Context) (io.ReadCloser, error) {
+	f, err := i.TracePipe()
+	if err != nil {
+		return nil, err
+	}
+
+	p := &ctxPipe{
+		ctx:  ctx,
+		f:    f,
+		done: make(chan struct{}),
+	}
+	go func() {
+		select {
+		case <-ctx.Done():
+			p.closeFile()
+		case <-p.done:
+		}
+	}()
+
+	return p, nil
+}
+
+type ctxPipe struct {
+	ctx       context.Context
+	f         io.ReadCloser
+	done      chan struct{}
+	doneOnce  sync.Once
+	closeOnce sync.Once
+	closeErr  error
+}
+
+func (p *ctxPipe) Read(b []byte) (int, error) {
+	n, err := p.f.Read(b)
+	if err != nil && p.ctx.Err() != nil {
+		return n, p.ctx.Err()
+	}
+	return n, err
+}
+
+func (p *ctxPipe) closeFile() error {
+	p.closeOnce.Do(func() {
+		p.closeErr = p.f.Close()
+	})
+	return p.closeErr
+}
+
+func (p *ctxPipe) Close() error {
+	p.doneOnce.Do(func() {
+		close(p.done)
+	})
+	return p.closeFile()
+}
+
+// StreamTrace reads trace_pipe line by line, calling fn for each line until
+// ctx is done or fn returns an error.
+func (i *Instance) StreamTrace(ctx context.Context, fn func(line string) error) error {
+	r, err := i.TracePipeContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	scanner := newLineScanner(r)
+	for scanner.Scan() {
+		if err := fn(scanner.Text()); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+// pollable is implemented by files registered with the runtime poller,
+// such as an *os.File for trace_pipe.
+type pollable interface {
+	SyscallConn() (syscall.RawConn, error)
+	SetReadDeadline(t time.Time) error
+}
+
+// WaitForTrace blocks until trace_pipe has data to read or ctx is done. It
+// waits using the runtime's poller (epoll) rather than a blocking read, so
+// no data is consumed. The kernel reports trace_pipe readable only once
+// buffer_percent of the ring buffer is filled, so with a nonzero
+// buffer_percent WaitForTrace can keep waiting while some events are
+// already buffered.
+func (i *Instance) WaitForTrace(ctx context.Context) error {
+	f, err := i.TracePipe()
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	p, ok := f.(pollable)
+	if !ok {
+		return fmt.Errorf("trace_pipe does not support polling")
+	}
+	rc, err := p.SyscallConn()
+	if err != nil {
+		return err
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			p.SetReadDeadline(time.Now())
+		case <-done:
+		}
+	}()
+
+	var (
+		waited  bool
+		pollErr error
+	)
+	err = rc.Read(func(fd uintptr) bool {
+		if waited {
+			return true
+		}
+		// The runtime poller is edge triggered and misses data buffered
+		// before the fd was registered, so check readiness directly.
+		// Returning false makes the poller wait for the fd to become
+		// readable before calling again.
+		var ready bool
+		ready, pollErr = pollReadable(fd)
+		waited = true
+		return ready || pollErr != nil
+	})
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if err != nil {
+		return err
+	}
+	return pollErr
+}
+
+// TracePipeNonblock opens trace_pipe for draining without waiting. Reads
+// consume events like TracePipe, but return io.EOF as soon as the buffer is
+// empty instead of blocking until new events arrive. Reading again later
+// returns events written since. Unlike the static trace file, the events
+// read are removed from the buffer.
+func (i *Instance) TracePipeNonblock() (io.ReadCloser, error) {
+	name := filepath.Join(i.path, "trace_pipe")
+	f, err := i.fsys().OpenFile(name, os.O_RDONLY|syscall.O_NONBLOCK, 0)
+	if err != nil {
+		return nil, i.wrapErr(err)
+	}
+	return &nonblockPipe{f: f, name: name}, nil
+}
+
+type nonblockPipe struct {
+	f File
+	// name is the path of trace_pipe, for errors from raw reads.
+	name string
+}
+
+func (p *nonblockPipe) Read(b []byte) (int, error) {
+	var (
+		n   int
+		err error
+	)
+	// The runtime poller turns EAGAIN into a wait for more data, so read
+	// through the raw fd to see EAGAIN directly.
+	if pf, ok := p.f.(pollable); ok {
+		rc, rcErr := pf.SyscallConn()
+		if rcErr != nil {
+			return 0, rcErr
+		}
+		rcErr = rc.Read(func(fd uintptr) bool {
+			n, err = syscall.Read(int(fd), b)
+			return err != syscall.EINTR
+		})
+		if rcErr != nil {
+			return 0, rcErr
+		}
+		if n < 0 {
+			n = 0
+		}
+		if err != nil {
+			err = &os.PathError{Op: "read", Path: p.name, Err: err}
+		}
+	} else {
+		n, err = p.f.Read(b)
+	}
+
+	if errors.Is(err, syscall.EAGAIN) || (n == 0 && err == nil && len(b) > 0) {
+		return 0, io.EOF
+	}
+	return n, err
+}
+
+func (p *nonblockPipe) Close() error {
+	return p.f.Close()
+}
@@ -0,0 +1,51 @@
+package tracefs
+
+import (
+	"errors"
+	"io"
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestNonblockPipeRead(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	defer w.Close()
+
+	p := &nonblockPipe{f: r, name: "/t/trace_pipe"}
+	buf := make([]byte, 16)
+	if _, err := p.Read(buf); err != io.EOF {
+		t.Errorf("Read of an empty pipe = %v, want io.EOF", err)
+	}
+
+	if _, err := w.Write([]byte("event\n")); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := p.Read(buf); err != nil || string(buf[:n]) != "event\n" {
+		t.Errorf("Read = %q, %v, want the written event", buf[:n], err)
+	}
+}
+
+func TestNonblockPipeReadError(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	defer w.Close()
+
+	// Reading the write end of a pipe fails with EBADF.
+	p := &nonblockPipe{f: w, name: "/t/trace_pipe"}
+	_, err = p.Read(make([]byte, 16))
+	var pathErr *os.PathError
+	if !errors.As(err, &pathErr) || pathErr.Path != "/t/trace_pipe" {
+		t.Fatalf("Read error = %#v, want an *os.PathError for /t/trace_pipe", err)
+	}
+	if !errors.Is(err, syscall.EBADF) {
+		t.Errorf("Read error %v does not match EBADF", err)
+	}
+}
@@ -0,0 +1,32 @@
+package tracefs
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+const pollIn = 0x1
+
+type pollFd struct {
+	fd      int32
+	events  int16
+	revents int16
+}
+
+// pollReadable reports whether fd has data to read right now. Unlike the
+// runtime poller, which is edge triggered, it also sees data that was
+// buffered before the fd was registered.
+func pollReadable(fd uintptr) (bool, error) {
+	pfd := pollFd{fd: int32(fd), events: pollIn}
+	var ts syscall.Timespec
+	for {
+		n, _, errno := syscall.Syscall6(syscall.SYS_PPOLL, uintptr(unsafe.Pointer(&pfd)), 1, uintptr(unsafe.Pointer(&ts)), 0, 0, 0)
+		if errno == syscall.EINTR {
+			continue
+		}
+		if errno != 0 {
+			return false, errno
+		}
+		return n > 0 && pfd.revents&pollIn != 0, nil
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package tracefs
+
+// pollReadable reports whether fd has data to read right now. Without a
+// level-triggered check it always reports false, leaving the wait to the
+// runtime poller.
+func pollReadable(fd uintptr) (bool, error) {
+	return false, nil
+}
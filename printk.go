@@ -0,0 +1,61 @@
+package tracefs
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// PrintkFormats returns the kernel's table of trace_printk and tracepoint
+// format string addresses, used to resolve format pointers in raw events.
+func (i *Instance) PrintkFormats() (map[uint64]string, error) {
+	lines, err := i.top().readLines("printk_formats")
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(map[uint64]string, len(lines))
+	for _, line := range lines {
+		// 0xffffffff81e0a1b0 : "Jump label: %s\n"
+		addrStr, format, ok := strings.Cut(line, " : ")
+		if !ok {
+			return nil, fmt.Errorf("invalid printk_formats line %q", line)
+		}
+		addr, err := strconv.ParseUint(strings.TrimSpace(addrStr), 0, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid printk_formats address %q: %w", addrStr, err)
+		}
+		out[addr] = unescapePrintkFormat(format)
+	}
+	return out, nil
+}
+
+// unescapePrintkFormat removes the surrounding quotes from a printk_formats
+// entry and decodes the \n, \t, \\ and \" escapes the kernel adds.
+func unescapePrintkFormat(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		s = s[1 : len(s)-1]
+	}
+
+	var b strings.Builder
+	for n := 0; n < len(s); n++ {
+		if s[n] != '\\' || n+1 == len(s) {
+			b.WriteByte(s[n])
+			continue
+		}
+		n++
+		switch s[n] {
+		case 'n':
+			b.WriteByte('\n')
+		case 't':
+			b.WriteByte('\t')
+		case '\\', '"':
+			b.WriteByte(s[n])
+		default:
+			b.WriteByte('\\')
+			b.WriteByte(s[n])
+		}
+	}
+	return b.String()
+}
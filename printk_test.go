@@ -0,0 +1,56 @@
+package tracefs
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestUnescapePrintkFormat(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{`"Jump label: %s\n"`, "Jump label: %s\n"},
+		{`"\tcpu=%d\tnr=%d\n"`, "\tcpu=%d\tnr=%d\n"},
+		{`"name=\"%s\" path=%s\\%s"`, `name="%s" path=%s\%s`},
+		{`"100%% done\x"`, `100%% done\x`},
+		{`"trailing\"`, `trailing\`},
+		{`unquoted`, "unquoted"},
+	}
+	for _, tt := range tests {
+		if got := unescapePrintkFormat(tt.in); got != tt.want {
+			t.Errorf("unescapePrintkFormat(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestPrintkFormats(t *testing.T) {
+	// From a 6.8 kernel's printk_formats.
+	fsys := newTracefs("/t", map[string]string{
+		"/t/printk_formats": `0xffffffff82a3d4e8 : "rcu_preempt"
+0xffffffff82a3d4f4 : "Start context switch"
+0xffffffff82a3d509 : "End context switch"
+0xffffffff82b05dc0 : "%s: %s\n"
+`,
+	})
+	i := RootInstance("/t", WithFS(fsys))
+
+	got, err := i.PrintkFormats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[uint64]string{
+		0xffffffff82a3d4e8: "rcu_preempt",
+		0xffffffff82a3d4f4: "Start context switch",
+		0xffffffff82a3d509: "End context switch",
+		0xffffffff82b05dc0: "%s: %s\n",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("PrintkFormats() = %q, want %q", got, want)
+	}
+
+	fsys.addFile("/t/printk_formats", "garbage\n")
+	if _, err := i.PrintkFormats(); err == nil {
+		t.Error("PrintkFormats accepted a line without an address")
+	}
+}
@@ -0,0 +1,137 @@
+package tracefs
+
+import (
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FunctionStat is a row of the function profiler output, merged across
+// CPUs.
+type FunctionStat struct {
+	Function string
+	Hit      uint64
+	Time     time.Duration
+	Avg      time.Duration
+	// S2 is the variance of the function's run time in microseconds
+	// squared.
+	S2 float64
+}
+
+// SetFunctionProfileEnabled turns the function profiler on or off.
+func (i *Instance) SetFunctionProfileEnabled(on bool) error {
+	v := "0"
+	if on {
+		v = "1"
+	}
+	return i.writeFile("function_profile_enabled", []byte(v))
+}
+
+// FunctionProfile reads every trace_stat/function* file and merges the
+// per-cpu rows by function name. Results are sorted by hit count, highest
+// first.
+func (i *Instance) FunctionProfile() ([]FunctionStat, error) {
+	entries, err := i.fsys().ReadDir(filepath.Join(i.path, "trace_stat"))
+	if err != nil {
+		return nil, i.wrapErr(err)
+	}
+
+	merged := make(map[string]*FunctionStat)
+	// sumSq is the sum of squared run times per function, used to merge
+	// the per-cpu variances.
+	sumSq := make(map[string]float64)
+
+	for _, e := range entries {
+		if !strings.HasPrefix(e.Name(), "function") {
+			continue
+		}
+		stats, err := i.readFunctionStats(filepath.Join("trace_stat", e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range stats {
+			m := merged[s.Function]
+			if m == nil {
+				m = &FunctionStat{Function: s.Function}
+				merged[s.Function] = m
+			}
+			m.Hit += s.Hit
+			m.Time += s.Time
+			avg := float64(s.Avg) / float64(time.Microsecond)
+			sumSq[s.Function] += float64(s.Hit) * (s.S2 + avg*avg)
+		}
+	}
+
+	out := make([]FunctionStat, 0, len(merged))
+	for name, m := range merged {
+		if m.Hit > 0 {
+			m.Avg = m.Time / time.Duration(m.Hit)
+			avg := float64(m.Avg) / float64(time.Microsecond)
+			m.S2 = sumSq[name]/float64(m.Hit) - avg*avg
+			if m.S2 < 0 {
+				m.S2 = 0
+			}
+		}
+		out = append(out, *m)
+	}
+	sort.Slice(out, func(a, b int) bool {
+		if out[a].Hit != out[b].Hit {
+			return out[a].Hit > out[b].Hit
+		}
+		return out[a].Function < out[b].Function
+	})
+
+	return out, nil
+}
+
+// readFunctionStats parses a single trace_stat/functionN file:
+//
+//	Function                  Hit    Time            Avg             s^2
+//	--------                  ---    ----            ---             ---
+//	schedule                 1234    12345.67 us     10.004 us       1234.567 us
+//
+// Without the function graph tracer only the Hit column is present.
+func (i *Instance) readFunctionStats(name string) ([]FunctionStat, error) {
+	lines, err := i.readLines(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []FunctionStat
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		hit, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			// Header and separator lines.
+			continue
+		}
+
+		s := FunctionStat{
+			Function: fields[0],
+			Hit:      hit,
+		}
+		// Remaining columns are value/unit pairs.
+		var vals []float64
+		for n := 2; n+1 < len(fields); n += 2 {
+			v, err := strconv.ParseFloat(fields[n], 64)
+			if err != nil {
+				break
+			}
+			vals = append(vals, v)
+		}
+		if len(vals) >= 2 {
+			s.Time = time.Duration(vals[0] * float64(time.Microsecond))
+			s.Avg = time.Duration(vals[1] * float64(time.Microsecond))
+		}
+		if len(vals) >= 3 {
+			s.S2 = vals[2]
+		}
+		out = append(out, s)
+	}
+	return out, nil
+}
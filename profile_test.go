@@ -0,0 +1,53 @@
+package tracefs
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestFunctionProfile(t *testing.T) {
+	// With function_graph available the profiler reports run times; the
+	// function1 file has only the Hit column, as without the graph tracer.
+	fsys := newTracefs("/t", map[string]string{
+		"/t/trace_stat/function0": `  Function                               Hit    Time            Avg             s^2
+  --------                               ---    ----            ---             ---
+  schedule                                 2    20.000 us       10.000 us       4.000 us
+  do_idle                                  1    5.500 us        5.500 us        0.000 us
+`,
+		"/t/trace_stat/function1": `  Function                               Hit    Time            Avg             s^2
+  --------                               ---    ----            ---             ---
+  schedule                                 2    40.000 us       20.000 us       0.000 us
+  rcu_note_context_switch                  7
+`,
+		"/t/trace_stat/branch_all": "ignored\n",
+	})
+	i := RootInstance("/t", WithFS(fsys))
+
+	got, err := i.FunctionProfile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 {
+		t.Fatalf("FunctionProfile returned %d functions, want 3: %+v", len(got), got)
+	}
+
+	// Sorted by hit count.
+	if got[0].Function != "rcu_note_context_switch" || got[0].Hit != 7 || got[0].Time != 0 {
+		t.Errorf("got[0] = %+v, want rcu_note_context_switch with 7 hits and no time", got[0])
+	}
+
+	s := got[1]
+	if s.Function != "schedule" || s.Hit != 4 || s.Time != 60*time.Microsecond || s.Avg != 15*time.Microsecond {
+		t.Errorf("got[1] = %+v, want schedule with 4 hits, 60us total, 15us avg", s)
+	}
+	// Samples with means 10 and 20 and variances 4 and 0 combine to a
+	// variance of 27.
+	if math.Abs(s.S2-27) > 1e-6 {
+		t.Errorf("schedule S2 = %v, want 27", s.S2)
+	}
+
+	if got[2].Function != "do_idle" || got[2].Time != 5500*time.Nanosecond {
+		t.Errorf("got[2] = %+v, want do_idle with 5.5us", got[2])
+	}
+}
@@ -0,0 +1,338 @@
+package tracefs
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// TracePipeRaw opens per_cpu/cpuN/trace_pipe_raw, which streams the cpu's
+// ring buffer as binary pages. Use a RawDecoder to turn the pages into
+// events.
+func (i *Instance) TracePipeRaw(cpu int) (io.ReadCloser, error) {
+	dir, err := i.cpuDir(cpu)
+	if err != nil {
+		return nil, err
+	}
+	return i.open(filepath.Join(dir, "trace_pipe_raw"))
+}
+
+// Ring buffer event type_len values with special meaning. Values 1-28 give
+// the data length in 4 byte words.
+const (
+	rbTypeDataLen    = 0
+	rbTypePadding    = 29
+	rbTypeTimeExtend = 30
+	rbTypeTimeStamp  = 31
+
+	rbTSShift    = 27
+	rbCommitMask = (1 << 27) - 1
+	// rbMissedEvents is set in the page commit field when events were lost
+	// before this page.
+	rbMissedEvents = 1 << 31
+)
+
+// pageLayout describes the ring buffer page header and the size of the
+// pages read from trace_pipe_raw.
+type pageLayout struct {
+	timestampOffset int
+	commitOffset    int
+	commitSize      int
+	dataOffset      int
+	pageSize        int
+}
+
+// headerLen is the number of bytes the page header fields occupy.
+func (l pageLayout) headerLen() int {
+	n := l.dataOffset
+	if end := l.timestampOffset + 8; end > n {
+		n = end
+	}
+	if end := l.commitOffset + l.commitSize; end > n {
+		n = end
+	}
+	return n
+}
+
+// defaultPageLayout is the page header layout on 64 bit kernels with the
+// default sub-buffer size of one page. The layout of the running kernel
+// can be read with Instance.HeaderPage.
+var defaultPageLayout = pageLayout{
+	timestampOffset: 0,
+	commitOffset:    8,
+	commitSize:      8,
+	dataOffset:      16,
+	pageSize:        os.Getpagesize(),
+}
+
+// RawEvent is a single record decoded from trace_pipe_raw.
+type RawEvent struct {
+	// Timestamp is in trace clock units (nanoseconds for the default
+	// clock).
+	Timestamp uint64
+	ID        int
+	// Format is the event's format, or nil if the decoder does not know
+	// the event ID.
+	Format *EventFormat
+	// Data is the raw record, starting with the common fields.
+	Data []byte
+	// MissedEvents is set on the first event of a page when the kernel
+	// dropped events before it.
+	MissedEvents bool
+}
+
+// Fields decodes every common and event field of e using its Format.
+// Integers are returned as int64 or uint64 depending on signedness, char
+// arrays and __data_loc strings as string, and other arrays as []byte.
+func (e *RawEvent) Fields() (map[string]any, error) {
+	if e.Format == nil {
+		return nil, fmt.Errorf("no format for event id %d", e.ID)
+	}
+
+	out := make(map[string]any, len(e.Format.CommonFields)+len(e.Format.Fields))
+	for _, fields := range [][]FormatField{e.Format.CommonFields, e.Format.Fields} {
+		for _, f := range fields {
+			v, err := decodeRawField(e.Data, f)
+			if err != nil {
+				return nil, err
+			}
+			out[f.Name] = v
+		}
+	}
+	return out, nil
+}
+
+// rawByteOrder is the byte order of ring buffer records. The records use
+// the kernel's native order; all architectures this package is used on are
+// little endian.
+var rawByteOrder = binary.LittleEndian
+
+func decodeRawField(data []byte, f FormatField) (any, error) {
+	if f.Offset+f.Size > len(data) {
+		return nil, fmt.Errorf("field %s at %d+%d exceeds record length %d", f.Name, f.Offset, f.Size, len(data))
+	}
+	b := data[f.Offset : f.Offset+f.Size]
+
+	if strings.HasPrefix(f.Type, "__data_loc") {
+		// A 32 bit value: offset in the low 16 bits, length in the high.
+		loc := rawByteOrder.Uint32(b)
+		off, n := int(loc&0xffff), int(loc>>16)
+		if off+n > len(data) {
+			return nil, fmt.Errorf("dynamic field %s at %d+%d exceeds record length %d", f.Name, off, n, len(data))
+		}
+		b = data[off : off+n]
+		if strings.Contains(f.Type, "char") {
+			return cString(b), nil
+		}
+		return b, nil
+	}
+
+	if f.IsArray {
+		if strings.Contains(f.Type, "char") {
+			return cString(b), nil
+		}
+		return b, nil
+	}
+
+	var u uint64
+	switch f.Size {
+	case 1:
+		u = uint64(b[0])
+	case 2:
+		u = uint64(rawByteOrder.Uint16(b))
+	case 4:
+		u = uint64(rawByteOrder.Uint32(b))
+	case 8:
+		u = rawByteOrder.Uint64(b)
+	default:
+		return b, nil
+	}
+
+	if f.Signed {
+		shift := 64 - 8*uint(f.Size)
+		return int64(u<<shift) >> shift, nil
+	}
+	return u, nil
+}
+
+func cString(b []byte) string {
+	if idx := bytes.IndexByte(b, 0); idx >= 0 {
+		b = b[:idx]
+	}
+	return string(b)
+}
+
+// RawDecoder decodes ring buffer pages read from trace_pipe_raw.
+type RawDecoder struct {
+	r       io.Reader
+	formats map[int]*EventFormat
+	layout  pageLayout
+
+	page   []byte
+	data   []byte
+	ts     uint64
+	missed bool
+}
+
+// NewRawDecoder returns a decoder reading pages from r. formats are used to
+// identify events by ID; see Instance.EventFormat. The decoder assumes the
+// 64 bit page layout and a page sized sub-buffer; see SetPageHeader.
+func NewRawDecoder(r io.Reader, formats ...*EventFormat) *RawDecoder {
+	d := &RawDecoder{
+		r:       r,
+		formats: make(map[int]*EventFormat, len(formats)),
+		layout:  defaultPageLayout,
+		page:    make([]byte, defaultPageLayout.pageSize),
+	}
+	for _, f := range formats {
+		d.formats[f.ID] = f
+	}
+	return d
+}
+
+// SetPageHeader makes the decoder use the page layout h, as returned by
+// Instance.HeaderPage, instead of the default 64 bit layout. The size of
+// the pages read is taken from h too, since it follows the sub-buffer size
+// (buffer_subbuf_size_kb) rather than the system page size.
+func (d *RawDecoder) SetPageHeader(h *PageHeader) {
+	d.layout = h.layout()
+	if len(d.page) != d.layout.pageSize {
+		d.page = make([]byte, d.layout.pageSize)
+	}
+}
+
+// Next returns the next event. It returns io.EOF when r is exhausted.
+func (d *RawDecoder) Next() (*RawEvent, error) {
+	for {
+		if len(d.data) == 0 {
+			if err := d.readPage(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		ev, err := d.nextEvent()
+		if err != nil {
+			return nil, err
+		}
+		if ev != nil {
+			return ev, nil
+		}
+	}
+}
+
+func (d *RawDecoder) readPage() error {
+	n, err := io.ReadFull(d.r, d.page)
+	if err == io.ErrUnexpectedEOF {
+		return fmt.Errorf("short ring buffer page: %d of %d bytes", n, len(d.page))
+	} else if err != nil {
+		return err
+	}
+
+	l := d.layout
+	page := d.page[:n]
+	if len(page) < l.headerLen() {
+		return fmt.Errorf("ring buffer page of %d bytes is smaller than its %d byte header", len(page), l.headerLen())
+	}
+	if l.commitSize != 4 && l.commitSize != 8 {
+		return fmt.Errorf("unsupported ring buffer commit size %d", l.commitSize)
+	}
+
+	d.ts = rawByteOrder.Uint64(page[l.timestampOffset:])
+	var commit uint64
+	if l.commitSize == 8 {
+		commit = rawByteOrder.Uint64(page[l.commitOffset:])
+	} else {
+		commit = uint64(rawByteOrder.Uint32(page[l.commitOffset:]))
+	}
+	d.missed = commit&rbMissedEvents != 0
+
+	size := int(commit & rbCommitMask)
+	if l.dataOffset+size > len(page) {
+		return fmt.Errorf("ring buffer page commit %d exceeds page size %d", size, len(page))
+	}
+	d.data = page[l.dataOffset : l.dataOffset+size]
+	return nil
+}
+
+var errTruncatedEvent = errors.New("truncated ring buffer event")
+
+// nextEvent consumes one record from the current page. It returns a nil
+// event for padding and time extend records.
+func (d *RawDecoder) nextEvent() (*RawEvent, error) {
+	if len(d.data) < 4 {
+		d.data = nil
+		return nil, nil
+	}
+
+	header := rawByteOrder.Uint32(d.data)
+	typeLen := header & 0x1f
+	delta := uint64(header >> 5)
+	rest := d.data[4:]
+
+	switch typeLen {
+	case rbTypePadding:
+		if delta == 0 || len(rest) < 4 {
+			// The rest of the page is padding.
+			d.data = nil
+			return nil, nil
+		}
+		n := int(rawByteOrder.Uint32(rest))
+		if n > len(rest) {
+			return nil, errTruncatedEvent
+		}
+		d.data = rest[n:]
+		return nil, nil
+	case rbTypeTimeExtend, rbTypeTimeStamp:
+		if len(rest) < 4 {
+			return nil, errTruncatedEvent
+		}
+		ext := uint64(rawByteOrder.Uint32(rest))<<rbTSShift + delta
+		if typeLen == rbTypeTimeExtend {
+			d.ts += ext
+		} else {
+			d.ts = ext
+		}
+		d.data = rest[4:]
+		return nil, nil
+	}
+
+	var n int
+	if typeLen == rbTypeDataLen {
+		if len(rest) < 4 {
+			return nil, errTruncatedEvent
+		}
+		n = int(rawByteOrder.Uint32(rest)) - 4
+		rest = rest[4:]
+	} else {
+		n = int(typeLen) * 4
+	}
+	if n < 0 || n > len(rest) {
+		return nil, errTruncatedEvent
+	}
+
+	d.ts += delta
+	ev := &RawEvent{
+		Timestamp:    d.ts,
+		Data:         append([]byte(nil), rest[:n]...),
+		MissedEvents: d.missed,
+	}
+	d.missed = false
+	if len(ev.Data) >= 2 {
+		ev.ID = int(rawByteOrder.Uint16(ev.Data))
+		ev.Format = d.formats[ev.ID]
+	}
+
+	// Records are padded to 4 byte alignment.
+	n = (n + 3) &^ 3
+	if n > len(rest) {
+		n = len(rest)
+	}
+	d.data = rest[n:]
+	return ev, nil
+}
@@ -0,0 +1,164 @@
+package tracefs
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"testing/iotest"
+)
+
+// testPageHeader describes 128 byte pages with the 64 bit header layout.
+var testPageHeader = &PageHeader{
+	TimestampOffset: 0,
+	TimestampSize:   8,
+	CommitOffset:    8,
+	CommitSize:      8,
+	DataOffset:      16,
+	DataSize:        112,
+}
+
+var testRawFormat = &EventFormat{
+	Name: "test",
+	ID:   42,
+	CommonFields: []FormatField{
+		{Name: "common_type", Type: "unsigned short", Offset: 0, Size: 2},
+	},
+	Fields: []FormatField{
+		{Name: "value", Type: "int", Offset: 4, Size: 4, Signed: true},
+	},
+}
+
+// pageBuilder assembles a ring buffer page for testPageHeader.
+type pageBuilder struct {
+	data []byte
+}
+
+func (b *pageBuilder) word(v uint32) {
+	var w [4]byte
+	rawByteOrder.PutUint32(w[:], v)
+	b.data = append(b.data, w[:]...)
+}
+
+func (b *pageBuilder) header(typeLen, delta uint32) {
+	b.word(typeLen | delta<<5)
+}
+
+// record appends an 8 byte event record with the given id and value.
+func (b *pageBuilder) record(id uint16, value int32) {
+	rec := make([]byte, 8)
+	rawByteOrder.PutUint16(rec, id)
+	rawByteOrder.PutUint32(rec[4:], uint32(value))
+	b.data = append(b.data, rec...)
+}
+
+func (b *pageBuilder) page(ts uint64, missed bool) []byte {
+	page := make([]byte, testPageHeader.DataOffset+testPageHeader.DataSize)
+	rawByteOrder.PutUint64(page, ts)
+	commit := uint64(len(b.data))
+	if missed {
+		commit |= rbMissedEvents
+	}
+	rawByteOrder.PutUint64(page[testPageHeader.CommitOffset:], commit)
+	copy(page[testPageHeader.DataOffset:], b.data)
+	return page
+}
+
+func TestRawDecoder(t *testing.T) {
+	var p1 pageBuilder
+	// A record whose length is encoded in type_len (2 words).
+	p1.header(2, 10)
+	p1.record(42, -7)
+	// A time extend of 1<<27 + 5.
+	p1.header(rbTypeTimeExtend, 5)
+	p1.word(1)
+	// A record with its length in the first array word.
+	p1.header(rbTypeDataLen, 3)
+	p1.word(12)
+	p1.record(42, 9)
+	// Padding to the end of the page.
+	p1.header(rbTypePadding, 0)
+
+	var p2 pageBuilder
+	p2.header(2, 1)
+	p2.record(7, 1)
+
+	input := append(p1.page(1000, true), p2.page(5000, false)...)
+	// Deliver the input a byte at a time to check that pages are read
+	// whole.
+	d := NewRawDecoder(iotest.OneByteReader(bytes.NewReader(input)), testRawFormat)
+	d.SetPageHeader(testPageHeader)
+
+	want := []struct {
+		ts     uint64
+		id     int
+		value  int64
+		missed bool
+	}{
+		{1010, 42, -7, true},
+		{1010 + 1<<27 + 5 + 3, 42, 9, false},
+		{5001, 7, 0, false},
+	}
+	for n, w := range want {
+		ev, err := d.Next()
+		if err != nil {
+			t.Fatalf("event %d: %v", n, err)
+		}
+		if ev.Timestamp != w.ts || ev.ID != w.id || ev.MissedEvents != w.missed {
+			t.Errorf("event %d = ts %d id %d missed %v, want ts %d id %d missed %v",
+				n, ev.Timestamp, ev.ID, ev.MissedEvents, w.ts, w.id, w.missed)
+		}
+		if w.id != testRawFormat.ID {
+			if ev.Format != nil {
+				t.Errorf("event %d has format %s for an unknown id", n, ev.Format.Name)
+			}
+			continue
+		}
+		fields, err := ev.Fields()
+		if err != nil {
+			t.Fatalf("event %d: %v", n, err)
+		}
+		if fields["common_type"] != uint64(42) || fields["value"] != w.value {
+			t.Errorf("event %d fields = %v, want common_type 42 value %d", n, fields, w.value)
+		}
+	}
+
+	if _, err := d.Next(); err != io.EOF {
+		t.Errorf("Next after the last page = %v, want io.EOF", err)
+	}
+}
+
+func TestRawDecoderShortPage(t *testing.T) {
+	var b pageBuilder
+	b.header(2, 1)
+	b.record(42, 1)
+	page := b.page(1, false)
+
+	d := NewRawDecoder(bytes.NewReader(page[:len(page)-1]))
+	d.SetPageHeader(testPageHeader)
+	if _, err := d.Next(); err == nil || errors.Is(err, io.EOF) {
+		t.Errorf("Next on a truncated page = %v, want a short page error", err)
+	}
+}
+
+func TestRawDecoderBadCommit(t *testing.T) {
+	page := make([]byte, testPageHeader.DataOffset+testPageHeader.DataSize)
+	rawByteOrder.PutUint64(page[testPageHeader.CommitOffset:], uint64(len(page)))
+
+	d := NewRawDecoder(bytes.NewReader(page))
+	d.SetPageHeader(testPageHeader)
+	if _, err := d.Next(); err == nil {
+		t.Error("Next accepted a commit larger than the page")
+	}
+}
+
+func TestRawDecoderHeaderLargerThanPage(t *testing.T) {
+	h := *testPageHeader
+	h.DataOffset, h.DataSize = 4, 0
+
+	d := NewRawDecoder(bytes.NewReader(make([]byte, 64)))
+	d.SetPageHeader(&h)
+	if _, err := d.Next(); err == nil {
+		t.Error("Next accepted a page smaller than its header")
+	}
+}
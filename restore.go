@@ -0,0 +1,156 @@
+package tracefs
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+// ResetTracer sets the current tracer back to nop.
+func (i *Instance) ResetTracer() error {
+	return i.SetTracer(NopTracer)
+}
+
+// Reset returns the instance to its default state, like trace-cmd reset:
+// tracing is turned off while the tracer is set to nop, the function
+// filters and pid filters are cleared, all events are disabled and their
+// filters removed, and the trace buffer is cleared. Tracing is then turned
+// back on, which is the kernel's default. Files the kernel does not provide
+// are skipped. The buffer size is left unchanged.
+//
+// For a child instance created for a single task, Destroy is usually
+// simpler; Reset is for instances that are kept.
+func (i *Instance) Reset() error {
+	steps := []func() error{
+		i.Disable,
+		i.ResetTracer,
+		func() error { return i.SetFtraceFilter(nil) },
+		func() error { return i.SetFtraceNotrace(nil) },
+		func() error { return i.SetFtracePIDs(nil) },
+		func() error { return i.SetEventPIDs(nil) },
+		func() error { return i.SetEventNotracePIDs(nil) },
+		func() error { return i.SetEvents(nil) },
+		func() error { return i.DisableEvent(Event{}) },
+		i.clearSystemFilters,
+		i.ClearTrace,
+		i.Enable,
+	}
+	for _, step := range steps {
+		if err := step(); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// clearSystemFilters removes the filters of every event by clearing each
+// system's filter.
+func (i *Instance) clearSystemFilters() error {
+	systems, err := i.EventSystems()
+	if err != nil {
+		return err
+	}
+	for _, system := range systems {
+		err := i.writeFile(filepath.Join(eventDir(Event{System: system}), "filter"), []byte("0"))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// WithTracer sets the current tracer to t, runs fn, and then restores the
+// previous tracer and tracing_on state. The state is restored even if fn
+// returns an error or panics. An error from fn takes precedence over an
+// error restoring state.
+func (i *Instance) WithTracer(t Tracer, fn func() error) (err error) {
+	prevTracer, err := i.CurrentTracer()
+	if err != nil {
+		return err
+	}
+	prevOn, err := i.On()
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		restoreErr := i.SetTracer(prevTracer)
+		if onErr := i.setOn(prevOn); restoreErr == nil {
+			restoreErr = onErr
+		}
+		if err == nil {
+			err = restoreErr
+		}
+	}()
+
+	if err := i.SetTracer(t); err != nil {
+		return err
+	}
+	return fn()
+}
+
+func (i *Instance) setOn(on bool) error {
+	if on {
+		return i.Enable()
+	}
+	return i.Disable()
+}
+
+// WhileTracing enables tracing, runs fn, and then restores the previous
+// tracing_on state, even if fn returns an error or panics.
+func (i *Instance) WhileTracing(fn func() error) (err error) {
+	prevOn, err := i.On()
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if restoreErr := i.setOn(prevOn); err == nil {
+			err = restoreErr
+		}
+	}()
+
+	if err := i.Enable(); err != nil {
+		return err
+	}
+	return fn()
+}
+
+// WithProbes adds and enables probes, runs fn, and then disables and
+// removes them. The probes are removed even if fn returns an error or
+// panics. If a probe cannot be added or enabled, the probes already added
+// are removed and fn is not run. An error from setup or fn takes
+// precedence over an error removing the probes.
+func (i *Instance) WithProbes(probes []*UprobeEvent, fn func() error) (err error) {
+	var added []*UprobeEvent
+	defer func() {
+		for n := len(added) - 1; n >= 0; n-- {
+			if removeErr := i.RemoveUprobeEvent(added[n]); err == nil {
+				err = removeErr
+			}
+		}
+	}()
+
+	for _, p := range probes {
+		if err := i.AddUprobeEvent(p); err != nil {
+			return err
+		}
+		// Enabling a probe without a name would enable every uprobe, so
+		// look up the name the kernel generated.
+		if p.Event == "" {
+			found, err := i.findUprobeEvent(p.Path, p.Offset)
+			if err != nil {
+				added = append(added, p)
+				return err
+			}
+			p = found
+		}
+		added = append(added, p)
+	}
+	for _, p := range added {
+		if err := i.EnableUprobe(p); err != nil {
+			return err
+		}
+	}
+	return fn()
+}
@@ -0,0 +1,90 @@
+package tracefs
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestReset(t *testing.T) {
+	// set_event_notrace_pid is missing, as on kernels before 5.8.
+	fsys := newTracefs("/t", map[string]string{
+		"/t/current_tracer":      "function\n",
+		"/t/set_ftrace_filter":   "schedule\n",
+		"/t/set_ftrace_notrace":  "",
+		"/t/set_ftrace_pid":      "12\n",
+		"/t/set_event_pid":       "12\n",
+		"/t/set_event":           "sched:sched_switch\n",
+		"/t/events/enable":       "X\n",
+		"/t/events/sched/enable": "X\n",
+		"/t/events/sched/filter": "prev_pid == 1\n",
+		"/t/trace":               "data\n",
+	})
+	i := RootInstance("/t", WithFS(fsys))
+
+	if err := i.Reset(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Tracing is off while the instance is reset and turned back on last.
+	want := []memWrite{
+		{"/t/tracing_on", "0"},
+		{"/t/current_tracer", "nop"},
+		{"/t/set_ftrace_filter", ""},
+		{"/t/set_ftrace_notrace", ""},
+		{"/t/set_ftrace_pid", ""},
+		{"/t/set_event_pid", ""},
+		{"/t/set_event", ""},
+		{"/t/events/enable", "0"},
+		{"/t/events/sched/filter", "0"},
+		{"/t/trace", ""},
+		{"/t/tracing_on", "1"},
+	}
+	if !reflect.DeepEqual(fsys.writes, want) {
+		t.Errorf("Reset writes:\n got %q\nwant %q", fsys.writes, want)
+	}
+}
+
+func TestWithProbesRemovesOnError(t *testing.T) {
+	fsys := newTracefs("/t", map[string]string{
+		"/t/uprobe_events":             "",
+		"/t/events/test/first/enable":  "0\n",
+		"/t/events/test/second/enable": "0\n",
+	})
+	i := RootInstance("/t", WithFS(fsys))
+
+	bin := filepath.Join(t.TempDir(), "bin")
+	if err := os.WriteFile(bin, make([]byte, 4096), 0644); err != nil {
+		t.Fatal(err)
+	}
+	first := &UprobeEvent{Group: "test", Event: "first", Path: bin, Offset: 0x100}
+	second := &UprobeEvent{Group: "test", Event: "second", Path: bin, Offset: 0x200}
+
+	fnErr := errors.New("fn failed")
+	err := i.WithProbes([]*UprobeEvent{first, second}, func() error {
+		if fsys.content("/t/events/test/second/enable") != "1" {
+			t.Error("probes are not enabled while fn runs")
+		}
+		return fnErr
+	})
+	if err != fnErr {
+		t.Errorf("WithProbes = %v, want the error from fn", err)
+	}
+
+	// The probes are disabled and removed in reverse order.
+	want := []memWrite{
+		{"/t/uprobe_events", first.Rule() + "\n"},
+		{"/t/uprobe_events", second.Rule() + "\n"},
+		{"/t/events/test/first/enable", "1"},
+		{"/t/events/test/second/enable", "1"},
+		{"/t/events/test/second/enable", "0"},
+		{"/t/uprobe_events", "-:test/second\n"},
+		{"/t/events/test/first/enable", "0"},
+		{"/t/uprobe_events", "-:test/first\n"},
+	}
+	if !reflect.DeepEqual(fsys.writes, want) {
+		t.Errorf("WithProbes writes:\n got %q\nwant %q", fsys.writes, want)
+	}
+}
@@ -0,0 +1,49 @@
+package tracefs
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"path/filepath"
+)
+
+var snapshotPath = "snapshot"
+
+// ErrSnapshotNotAllocated is returned by ReadSnapshot when no snapshot
+// buffer is allocated.
+var ErrSnapshotNotAllocated = errors.New("snapshot buffer not allocated")
+
+// TakeSnapshot allocates the snapshot buffer if needed and swaps it with the
+// current trace buffer.
+func (i *Instance) TakeSnapshot() error {
+	return i.writeFile(snapshotPath, []byte("1"))
+}
+
+// ClearSnapshot clears the snapshot buffer without freeing it.
+func (i *Instance) ClearSnapshot() error {
+	return i.writeFile(snapshotPath, []byte("2"))
+}
+
+// FreeSnapshot frees the snapshot buffer.
+func (i *Instance) FreeSnapshot() error {
+	return i.writeFile(snapshotPath, []byte("0"))
+}
+
+// ReadSnapshot returns the contents of the snapshot buffer.
+func (i *Instance) ReadSnapshot() ([]byte, error) {
+	data, err := readPath(i.fsys(), filepath.Join(i.path, snapshotPath))
+	if err != nil {
+		return nil, i.wrapErr(err)
+	}
+	if bytes.Contains(data, []byte("* Snapshot is freed *")) {
+		return nil, ErrSnapshotNotAllocated
+	}
+	return data, nil
+}
+
+// SnapshotReader opens the snapshot file for streaming. If no snapshot is
+// allocated the kernel returns a short comment saying so rather than an
+// error; use ReadSnapshot to detect this.
+func (i *Instance) SnapshotReader() (io.ReadCloser, error) {
+	return i.open(snapshotPath)
+}
@@ -0,0 +1,105 @@
+package tracefs
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	stackTracePath       = "stack_trace"
+	stackMaxSizePath     = "stack_max_size"
+	stackTraceFilterPath = "stack_trace_filter"
+	stackTracerSysctl    = "/proc/sys/kernel/stack_tracer_enabled"
+)
+
+// StackFrame is one row of the stack_trace table.
+type StackFrame struct {
+	// Index is the row number, 0 being the deepest frame.
+	Index int
+	// Depth is the stack usage in bytes from this frame to the top of the
+	// stack.
+	Depth int
+	// Size is the stack used by this frame in bytes.
+	Size     int
+	Location string
+}
+
+// SetStackTracerEnabled turns the stack tracer on or off through the
+// kernel.stack_tracer_enabled sysctl. The stack tracer files only exist in
+// the root instance. The tracer can also be enabled at boot with the
+// stacktrace kernel parameter.
+func (i *Instance) SetStackTracerEnabled(on bool) error {
+	v := "0"
+	if on {
+		v = "1"
+	}
+	return i.writePath(stackTracerSysctl, []byte(v), false)
+}
+
+// StackTrace returns the raw contents of stack_trace, the deepest kernel
+// stack seen since the last reset.
+func (i *Instance) StackTrace() (string, error) {
+	data, err := i.readFile(stackTracePath)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// StackTraceFrames returns stack_trace parsed into its index, depth, size
+// and location columns.
+func (i *Instance) StackTraceFrames() ([]StackFrame, error) {
+	lines, err := i.readLines(stackTracePath)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []StackFrame
+	for _, line := range lines {
+		// Rows look like "  0)     4376      48   __slab_alloc+0x2a/0x60".
+		// The header and separator lines have no ")" after the index.
+		fields := strings.Fields(line)
+		if len(fields) < 4 || !strings.HasSuffix(fields[0], ")") {
+			continue
+		}
+		index, err := strconv.Atoi(strings.TrimSuffix(fields[0], ")"))
+		if err != nil {
+			continue
+		}
+		frame := StackFrame{
+			Index:    index,
+			Location: strings.Join(fields[3:], " "),
+		}
+		if frame.Depth, err = strconv.Atoi(fields[1]); err != nil {
+			return nil, fmt.Errorf("invalid stack_trace row %q: %w", line, err)
+		}
+		if frame.Size, err = strconv.Atoi(fields[2]); err != nil {
+			return nil, fmt.Errorf("invalid stack_trace row %q: %w", line, err)
+		}
+		out = append(out, frame)
+	}
+	return out, nil
+}
+
+// StackMaxSize returns the size in bytes of the deepest stack seen.
+func (i *Instance) StackMaxSize() (int, error) {
+	return i.readInt(stackMaxSizePath)
+}
+
+// ResetStackMaxSize resets the recorded maximum stack size.
+func (i *Instance) ResetStackMaxSize() error {
+	return i.writeInt(stackMaxSizePath, 0)
+}
+
+// SetStackTraceFilter limits the stack tracer to functions matching
+// patterns, using the same syntax as SetFtraceFilter. An empty list clears
+// the filter.
+func (i *Instance) SetStackTraceFilter(patterns []string) error {
+	return i.replaceFile(stackTraceFilterPath, []byte(strings.Join(patterns, "\n")))
+}
+
+// StackTraceFilter returns the stack tracer function filter.
+func (i *Instance) StackTraceFilter() ([]string, error) {
+	return i.readFilterFile(stackTraceFilterPath)
+}
@@ -0,0 +1,43 @@
+package tracefs
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestStackTraceFrames(t *testing.T) {
+	fsys := newTracefs("/t", map[string]string{
+		"/t/stack_trace": `        Depth    Size   Location    (4 entries)
+        -----    ----   --------
+  0)     2928     224   update_sd_lb_stats.constprop.0+0x110/0x6a0
+  1)     2704     160   find_busiest_group+0x41/0x320
+  2)     2544     256   load_balance+0x1a4/0xb00
+  3)     2288      80   __schedule+0x3c1/0x1480
+`,
+	})
+	i := RootInstance("/t", WithFS(fsys))
+
+	got, err := i.StackTraceFrames()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []StackFrame{
+		{Index: 0, Depth: 2928, Size: 224, Location: "update_sd_lb_stats.constprop.0+0x110/0x6a0"},
+		{Index: 1, Depth: 2704, Size: 160, Location: "find_busiest_group+0x41/0x320"},
+		{Index: 2, Depth: 2544, Size: 256, Location: "load_balance+0x1a4/0xb00"},
+		{Index: 3, Depth: 2288, Size: 80, Location: "__schedule+0x3c1/0x1480"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("StackTraceFrames:\n got %+v\nwant %+v", got, want)
+	}
+}
+
+func TestStackTraceFramesInvalid(t *testing.T) {
+	fsys := newTracefs("/t", map[string]string{
+		"/t/stack_trace": "  0)     abc     224   schedule+0x10/0x20\n",
+	})
+	i := RootInstance("/t", WithFS(fsys))
+	if _, err := i.StackTraceFrames(); err == nil {
+		t.Error("StackTraceFrames accepted a non-numeric depth")
+	}
+}
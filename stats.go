@@ -0,0 +1,117 @@
+package tracefs
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RingStats are the per-cpu ring buffer statistics from per_cpu/cpuN/stats.
+type RingStats struct {
+	Entries       uint64
+	Overrun       uint64
+	CommitOverrun uint64
+	Bytes         uint64
+	OldestEventTS time.Duration
+	NowTS         time.Duration
+	DroppedEvents uint64
+	ReadEvents    uint64
+}
+
+// CPUStats returns the ring buffer statistics for cpu. A growing Overrun
+// count means events are being lost because the buffer is too small.
+func (i *Instance) CPUStats(cpu int) (*RingStats, error) {
+	dir, err := i.cpuDir(cpu)
+	if err != nil {
+		return nil, err
+	}
+
+	lines, err := i.readLines(filepath.Join(dir, "stats"))
+	if err != nil {
+		return nil, err
+	}
+
+	var s RingStats
+	for _, line := range lines {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+
+		var (
+			u  *uint64
+			ts *time.Duration
+		)
+		switch key {
+		case "entries":
+			u = &s.Entries
+		case "overrun":
+			u = &s.Overrun
+		case "commit overrun":
+			u = &s.CommitOverrun
+		case "bytes":
+			u = &s.Bytes
+		case "dropped events":
+			u = &s.DroppedEvents
+		case "read events":
+			u = &s.ReadEvents
+		case "oldest event ts":
+			ts = &s.OldestEventTS
+		case "now ts":
+			ts = &s.NowTS
+		default:
+			continue
+		}
+
+		if u != nil {
+			*u, err = strconv.ParseUint(value, 10, 64)
+		} else {
+			*ts, err = parseTraceTimestamp(value)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid cpu%d stats line %q: %w", cpu, line, err)
+		}
+	}
+
+	return &s, nil
+}
+
+// AllCPUStats returns the ring buffer statistics for every cpu, keyed by cpu
+// number.
+func (i *Instance) AllCPUStats() (map[int]*RingStats, error) {
+	cpus, err := i.cpus()
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(map[int]*RingStats, len(cpus))
+	for _, cpu := range cpus {
+		s, err := i.CPUStats(cpu)
+		if err != nil {
+			return nil, err
+		}
+		out[cpu] = s
+	}
+	return out, nil
+}
+
+// cpus returns the cpu numbers with a per_cpu directory.
+func (i *Instance) cpus() ([]int, error) {
+	entries, err := i.fsys().ReadDir(filepath.Join(i.path, "per_cpu"))
+	if err != nil {
+		return nil, i.wrapErr(err)
+	}
+
+	var out []int
+	for _, e := range entries {
+		cpu, err := strconv.Atoi(strings.TrimPrefix(e.Name(), "cpu"))
+		if err != nil || !strings.HasPrefix(e.Name(), "cpu") {
+			continue
+		}
+		out = append(out, cpu)
+	}
+	return out, nil
+}
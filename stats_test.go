@@ -0,0 +1,59 @@
+package tracefs
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCPUStats(t *testing.T) {
+	fsys := newTracefs("/t", map[string]string{
+		"/t/per_cpu/cpu0/stats": `entries: 129
+overrun: 3
+commit overrun: 0
+bytes: 6356
+oldest event ts: 14581.427306
+now ts: 14593.005485
+dropped events: 0
+read events: 89
+`,
+		"/t/per_cpu/cpu1/stats": "entries: 0\n",
+	})
+	i := RootInstance("/t", WithFS(fsys))
+
+	got, err := i.CPUStats(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &RingStats{
+		Entries:       129,
+		Overrun:       3,
+		Bytes:         6356,
+		OldestEventTS: 14581*time.Second + 427306*time.Microsecond,
+		NowTS:         14593*time.Second + 5485*time.Microsecond,
+		ReadEvents:    89,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("CPUStats(0) = %+v, want %+v", got, want)
+	}
+
+	all, err := i.AllCPUStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 2 || !reflect.DeepEqual(all[0], want) || all[1].Entries != 0 {
+		t.Errorf("AllCPUStats = %v, want cpus 0 and 1", all)
+	}
+
+	if _, err := i.CPUStats(2); err == nil {
+		t.Error("CPUStats of an offline cpu succeeded")
+	}
+}
+
+func TestCPUStatsInvalid(t *testing.T) {
+	fsys := newTracefs("/t", map[string]string{"/t/per_cpu/cpu0/stats": "entries: lots\n"})
+	i := RootInstance("/t", WithFS(fsys))
+	if _, err := i.CPUStats(0); err == nil {
+		t.Error("CPUStats accepted a non-numeric entry count")
+	}
+}
@@ -0,0 +1,77 @@
+package tracefs
+
+import "fmt"
+
+// Status is a snapshot of an instance's main settings.
+type Status struct {
+	Tracer        Tracer
+	On            bool
+	BufferSizeKB  int
+	Clock         string
+	EnabledEvents int
+	// Errors holds the read error for each field that could not be read,
+	// keyed by field name (e.g. "Clock"). Those fields are left zero.
+	Errors map[string]error
+}
+
+// Status reads the instance's tracer, tracing_on state, buffer size, trace
+// clock and number of enabled events. Fields that cannot be read, for
+// example because an older kernel lacks the file, are recorded in
+// Status.Errors. An error is returned only if no field could be read.
+func (i *Instance) Status() (*Status, error) {
+	s := &Status{Errors: map[string]error{}}
+
+	// read counts the fields that were read successfully.
+	read := 0
+	record := func(field string, err error) {
+		if err != nil {
+			s.Errors[field] = err
+		} else {
+			read++
+		}
+	}
+
+	var err error
+	s.Tracer, err = i.CurrentTracer()
+	record("Tracer", err)
+	s.On, err = i.On()
+	record("On", err)
+	s.BufferSizeKB, err = i.BufferSizeKB()
+	record("BufferSizeKB", err)
+	s.Clock, err = i.TraceClock()
+	record("Clock", err)
+	events, err := i.ActiveEvents()
+	record("EnabledEvents", err)
+	s.EnabledEvents = len(events)
+
+	if read == 0 {
+		return nil, fmt.Errorf("read status of %s: %w", i.path, s.Errors["Tracer"])
+	}
+	return s, nil
+}
+
+// IsActive reports whether the instance is tracing anything: tracing_on is
+// set and either the current tracer is not nop or some event is enabled.
+// Enabled kprobe and uprobe events are included, since they are listed in
+// set_event like other events. The checks stop at the first that decides
+// the result.
+func (i *Instance) IsActive() (bool, error) {
+	on, err := i.On()
+	if err != nil || !on {
+		return false, err
+	}
+
+	tracer, err := i.CurrentTracer()
+	if err != nil {
+		return false, err
+	}
+	if tracer != NopTracer {
+		return true, nil
+	}
+
+	events, err := i.ActiveEvents()
+	if err != nil {
+		return false, err
+	}
+	return len(events) > 0, nil
+}
@@ -0,0 +1,84 @@
+package tracefs
+
+import (
+	"fmt"
+	"strings"
+)
+
+var syntheticEventsPath = "synthetic_events"
+
+// SyntheticEvent is a user defined event, typically generated by
+// histogram triggers (e.g. for inter-event latencies).
+type SyntheticEvent struct {
+	Name   string
+	Fields []SyntheticField
+}
+
+// SyntheticField is a single field of a SyntheticEvent.
+type SyntheticField struct {
+	// Type is a C type such as "u64", "pid_t" or "char[16]".
+	Type string
+	Name string
+}
+
+// Definition returns the synthetic_events definition for e, e.g.
+// "wakeup_latency u64 lat; pid_t pid".
+func (e SyntheticEvent) Definition() string {
+	fields := make([]string, len(e.Fields))
+	for n, f := range e.Fields {
+		fields[n] = f.Type + " " + f.Name
+	}
+
+	if len(fields) == 0 {
+		return e.Name
+	}
+	return e.Name + " " + strings.Join(fields, "; ")
+}
+
+// AddSyntheticEvent defines e. It then appears under events/synthetic.
+func (i *Instance) AddSyntheticEvent(e SyntheticEvent) error {
+	if !validName(e.Name) {
+		return fmt.Errorf("%w: invalid synthetic event name %q", ErrInvalidValue, e.Name)
+	}
+	return i.top().appendLine(syntheticEventsPath, e.Definition())
+}
+
+// RemoveSyntheticEvent deletes the synthetic event e.
+func (i *Instance) RemoveSyntheticEvent(e SyntheticEvent) error {
+	return i.top().appendLine(syntheticEventsPath, "!"+e.Name)
+}
+
+// ListSyntheticEvents returns the defined synthetic events.
+func (i *Instance) ListSyntheticEvents() ([]SyntheticEvent, error) {
+	lines, err := i.top().readLines(syntheticEventsPath)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]SyntheticEvent, 0, len(lines))
+	for _, line := range lines {
+		out = append(out, parseSyntheticEvent(line))
+	}
+	return out, nil
+}
+
+// parseSyntheticEvent is the inverse of SyntheticEvent.Definition. The
+// kernel lists events with a tab after the name, e.g.
+// "wakeup_latency\tu64 lat; pid_t pid".
+func parseSyntheticEvent(def string) SyntheticEvent {
+	def = strings.TrimSpace(def)
+	name, rest := def, ""
+	if idx := strings.IndexAny(def, " \t"); idx >= 0 {
+		name, rest = def[:idx], def[idx+1:]
+	}
+	e := SyntheticEvent{Name: name}
+	for _, part := range strings.Split(rest, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		typ, fieldName := splitFieldDecl(part)
+		e.Fields = append(e.Fields, SyntheticField{Type: typ, Name: fieldName})
+	}
+	return e
+}
@@ -0,0 +1,63 @@
+package tracefs
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestListSyntheticEvents(t *testing.T) {
+	// As written by the kernel's synth_events_seq_show: a tab after the
+	// name and "; " between fields.
+	fsys := newTracefs("/t", map[string]string{
+		"/t/synthetic_events": "wakeup_latency\tu64 lat; pid_t pid\n" +
+			"switch_comm\tchar[16] comm; unsigned int prio\n" +
+			"empty\t\n",
+	})
+	i := RootInstance("/t", WithFS(fsys))
+
+	got, err := i.ListSyntheticEvents()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []SyntheticEvent{
+		{Name: "wakeup_latency", Fields: []SyntheticField{{Type: "u64", Name: "lat"}, {Type: "pid_t", Name: "pid"}}},
+		{Name: "switch_comm", Fields: []SyntheticField{{Type: "char[16]", Name: "comm"}, {Type: "unsigned int", Name: "prio"}}},
+		{Name: "empty"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ListSyntheticEvents:\n got %+v\nwant %+v", got, want)
+	}
+}
+
+func TestSyntheticEventDefinition(t *testing.T) {
+	e := SyntheticEvent{Name: "wakeup_latency", Fields: []SyntheticField{{Type: "u64", Name: "lat"}, {Type: "pid_t", Name: "pid"}}}
+	def := e.Definition()
+	if def != "wakeup_latency u64 lat; pid_t pid" {
+		t.Errorf("Definition() = %q", def)
+	}
+	if got := parseSyntheticEvent(def); !reflect.DeepEqual(got, e) {
+		t.Errorf("parseSyntheticEvent(%q) = %+v, want %+v", def, got, e)
+	}
+}
+
+func TestAddSyntheticEventChild(t *testing.T) {
+	fsys := newTracefs("/t", map[string]string{"/t/synthetic_events": ""})
+	fsys.dirs["/t/instances/child"] = true
+	root := RootInstance("/t", WithFS(fsys))
+	child, _, err := root.Instance("child")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := SyntheticEvent{Name: "lat", Fields: []SyntheticField{{Type: "u64", Name: "delta"}}}
+	if err := child.AddSyntheticEvent(e); err != nil {
+		t.Fatal(err)
+	}
+	if err := child.RemoveSyntheticEvent(e); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"lat u64 delta\n", "!lat\n"}
+	if got := fsys.written("/t/synthetic_events"); !reflect.DeepEqual(got, want) {
+		t.Errorf("synthetic_events writes = %q, want %q", got, want)
+	}
+}
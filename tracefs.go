@@ -2,65 +2,181 @@ package tracefs
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
+	"syscall"
 )
 
+// Instance is a tracefs instance: the root tracing directory or a child
+// under instances/. Writes to control files are serialized by a lock
+// shared by the root instance returned from RootInstance and every
+// Instance derived from it (copies, children and Root), so methods may be
+// called from multiple goroutines without interleaving partial writes.
+// Each method is still a separate sequence of file operations; callers
+// that need several calls to apply atomically must coordinate themselves.
+// Separate RootInstance calls for the same mount do not share a lock.
 type Instance struct {
 	isRoot bool
 	path   string
 	name   string
+	cfg    config
+	// mu is shared by all instances derived from the same RootInstance.
+	mu *sync.Mutex
 }
 
 var (
-	rootPath        = "/sys/kernel/tracing"
+	rootPath = "/sys/kernel/tracing"
+	// DefaultInstance is the root instance used by the package level
+	// functions such as NewInstance. Its path starts as
+	// /sys/kernel/tracing and is replaced by the mount point found by
+	// FindTracefs the first time one of those functions runs, unless it
+	// was changed before then.
 	DefaultInstance = RootInstance(rootPath)
+
+	defaultOnce sync.Once
 )
 
+// defaultInstance returns DefaultInstance, resolving its path on first use.
+func defaultInstance() *Instance {
+	defaultOnce.Do(func() {
+		if DefaultInstance.path != rootPath {
+			return
+		}
+		if path, err := FindTracefs(); err == nil {
+			DefaultInstance.path = path
+		}
+	})
+	return &DefaultInstance
+}
+
 func (i *Instance) Name() string {
 	return i.name
 }
 
-func RootInstance(path string) Instance {
+// Path returns the filesystem path of the instance.
+func (i *Instance) Path() string {
+	return i.path
+}
+
+// IsRoot reports whether i is a root (top level) instance.
+func (i *Instance) IsRoot() bool {
+	return i.isRoot
+}
+
+// Root returns the root instance i belongs to. For a root instance this is
+// i itself; for a child it is derived by stripping the instances/<name>
+// suffix from the path.
+func (i *Instance) Root() Instance {
+	if i.isRoot {
+		return *i
+	}
+
+	dir := filepath.Dir(filepath.Clean(i.path))
+	if filepath.Base(dir) == "instances" {
+		dir = filepath.Dir(dir)
+	}
 	return Instance{
+		isRoot: true,
+		name:   "*Default*",
+		path:   dir,
+		cfg:    i.cfg,
+		mu:     i.mu,
+	}
+}
+
+// top returns the root instance for operations on files that only exist
+// in the root, such as the probe definition files. For a root instance it
+// is i itself.
+func (i *Instance) top() *Instance {
+	if i.isRoot {
+		return i
+	}
+	root := i.Root()
+	return &root
+}
+
+// Open opens the named file under the instance path for reading. It is an
+// escape hatch for control files the package does not model. name must be
+// relative to the instance and must not leave it.
+func (i *Instance) Open(name string) (File, error) {
+	return i.OpenFile(name, os.O_RDONLY)
+}
+
+// OpenFile opens the named file under the instance path with flag (e.g.
+// os.O_WRONLY). name is validated as for Open.
+func (i *Instance) OpenFile(name string, flag int) (File, error) {
+	p, err := i.instanceFile(name)
+	if err != nil {
+		return nil, err
+	}
+	f, err := i.fsys().OpenFile(p, flag, i.writeMode())
+	if err != nil {
+		return nil, i.wrapErr(err)
+	}
+	return f, nil
+}
+
+// instanceFile returns the full path of name, which must be a relative path
+// that stays within the instance directory.
+func (i *Instance) instanceFile(name string) (string, error) {
+	clean := filepath.Clean(name)
+	if name == "" || filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%w: path %q is not within the instance", ErrInvalidValue, name)
+	}
+	return filepath.Join(i.path, clean), nil
+}
+
+// RootInstance returns the root instance for tracefs mounted at path.
+func RootInstance(path string, opts ...Option) Instance {
+	i := Instance{
 		isRoot: true,
 		name:   "*Default*",
 		path:   path,
+		mu:     new(sync.Mutex),
+	}
+	for _, opt := range opts {
+		opt(&i.cfg)
 	}
+	return i
 }
 
 func (i Instance) ChildInstances() ([]Instance, error) {
 	if !i.isRoot {
-		return nil, fmt.Errorf("Cannot get ChildInstances for non-root instance")
+		return nil, fmt.Errorf("cannot get ChildInstances for non-root instance: %w", ErrNotRoot)
 	}
 
 	instanceDir := filepath.Join(i.path, "instances")
-	entries, err := os.ReadDir(instanceDir)
+	entries, err := i.fsys().ReadDir(instanceDir)
 	if err != nil {
-		return nil, err
+		return nil, i.wrapErr(err)
 	}
 	out := make([]Instance, len(entries))
-	for i, e := range entries {
-		out[i] = Instance{
-			name: e.Name(),
-			path: filepath.Join(instanceDir, e.Name()),
-		}
+	for n, e := range entries {
+		out[n] = *i.child(e.Name())
 	}
 
 	return out, nil
 }
 
 func ListInstances() ([]Instance, error) {
-	return DefaultInstance.ChildInstances()
+	return defaultInstance().ChildInstances()
 }
 
 // Create a new child tracer instance. This only works when called on the root instance.
 func NewInstance(name string) (*Instance, error) {
-	return DefaultInstance.NewInstance(name)
+	return defaultInstance().NewInstance(name)
+}
+
+// GetOrCreateInstance returns the named child of the default instance,
+// creating it if needed.
+func GetOrCreateInstance(name string) (*Instance, error) {
+	return defaultInstance().GetOrCreateInstance(name)
 }
 
 type Tracer string
@@ -70,23 +186,96 @@ const (
 	FunctionTracer      Tracer = "function"
 	WakeupTracer        Tracer = "wakeup"
 	WakeupRTTracer      Tracer = "wakeup_rt"
-	WakeupDLTracer      Tracer = "wakup_dl"
+	WakeupDLTracer      Tracer = "wakeup_dl"
 	FunctionGraphTracer Tracer = "function_graph"
 	MMIOTraceTracer     Tracer = "mmiotrace"
 	BlkTracer           Tracer = "blk"
 	HWLatTracer         Tracer = "hwlat"
 )
 
+// AllTracers returns the Tracer constants defined by this package. The
+// running kernel may provide a subset or other tracers; see
+// AvailableTracers.
+func AllTracers() []Tracer {
+	return []Tracer{
+		NopTracer,
+		FunctionTracer,
+		WakeupTracer,
+		WakeupRTTracer,
+		WakeupDLTracer,
+		FunctionGraphTracer,
+		MMIOTraceTracer,
+		BlkTracer,
+		HWLatTracer,
+	}
+}
+
+// Known reports whether t is one of the Tracer constants.
+func (t Tracer) Known() bool {
+	for _, known := range AllTracers() {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
 func (i *Instance) readFile(name string) ([]byte, error) {
-	data, err := ioutil.ReadFile(filepath.Join(i.path, name))
+	data, err := readPath(i.fsys(), filepath.Join(i.path, name))
 	if err != nil {
-		return nil, err
+		return nil, i.wrapErr(err)
 	}
 	return bytes.TrimSpace(data), nil
 }
 
+// writeFile writes a value to a control file.
 func (i *Instance) writeFile(name string, b []byte) error {
-	return ioutil.WriteFile(filepath.Join(i.path, name), b, 0777)
+	return i.wrapErr(i.writePath(filepath.Join(i.path, name), b, false))
+}
+
+// replaceFile truncates a list or buffer file, clearing its contents, and
+// then writes b.
+func (i *Instance) replaceFile(name string, b []byte) error {
+	return i.wrapErr(i.writePath(filepath.Join(i.path, name), b, true))
+}
+
+// open opens the named file for reading.
+func (i *Instance) open(name string) (io.ReadCloser, error) {
+	f, err := i.fsys().OpenFile(filepath.Join(i.path, name), os.O_RDONLY, 0)
+	if err != nil {
+		return nil, i.wrapErr(err)
+	}
+	return f, nil
+}
+
+// readLines reads a file and returns its non-empty lines.
+func (i *Instance) readLines(name string) ([]string, error) {
+	data, err := i.readFile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []string
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" {
+			out = append(out, line)
+		}
+	}
+	return out, nil
+}
+
+// readInt reads a file containing a single integer.
+func (i *Instance) readInt(name string) (int, error) {
+	data, err := i.readFile(name)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(string(data))
+}
+
+func (i *Instance) writeInt(name string, v int) error {
+	return i.writeFile(name, []byte(strconv.Itoa(v)))
 }
 
 var (
@@ -104,9 +293,54 @@ func (i *Instance) CurrentTracer() (Tracer, error) {
 	return Tracer(tracer), nil
 }
 
-// SetTracer sets current_tracer to t.
+// SetTracer sets current_tracer to t. If the kernel rejects t because it is
+// not an available tracer, the returned error says so. With WithValidation
+// the check is done before writing.
 func (i *Instance) SetTracer(t Tracer) error {
-	return i.writeFile(curTracerPath, []byte(t))
+	if i.cfg.validate {
+		if err := i.checkTracerAvailable(t, ErrInvalidValue); err != nil {
+			return err
+		}
+	}
+
+	err := i.writeFile(curTracerPath, []byte(t))
+	if errors.Is(err, syscall.EINVAL) {
+		if availErr := i.checkTracerAvailable(t, err); availErr != nil {
+			return availErr
+		}
+	}
+	return err
+}
+
+// checkTracerAvailable returns an error wrapping cause if t is not in
+// available_tracers. It returns nil if t is available or the list cannot be
+// read.
+func (i *Instance) checkTracerAvailable(t Tracer, cause error) error {
+	available, err := i.AvailableTracers()
+	if err != nil {
+		return nil
+	}
+	for _, a := range available {
+		if a == t {
+			return nil
+		}
+	}
+	return fmt.Errorf("tracer %q not available (available: %v): %w", t, available, cause)
+}
+
+// AvailableTracers returns the tracers compiled into the running kernel.
+func (i *Instance) AvailableTracers() ([]Tracer, error) {
+	data, err := i.readFile("available_tracers")
+	if err != nil {
+		return nil, err
+	}
+
+	fields := strings.Fields(string(data))
+	out := make([]Tracer, len(fields))
+	for n, f := range fields {
+		out[n] = Tracer(f)
+	}
+	return out, nil
 }
 
 // On returns true if tracing_on is set to 1.
@@ -138,64 +372,359 @@ func (i *Instance) Disable() error {
 // Create a new child tracer instance. This only works when called on the root instance.
 func (i *Instance) NewInstance(name string) (*Instance, error) {
 	if !i.isRoot {
-		return nil, fmt.Errorf("must be called on a root instance")
+		return nil, fmt.Errorf("NewInstance must be called on a root instance: %w", ErrNotRoot)
 	}
 
-	childPath := filepath.Join(i.path, "instances", name)
-	err := os.Mkdir(childPath, 0777)
+	if err := validateInstanceName(name); err != nil {
+		return nil, err
+	}
+
+	err := i.fsys().Mkdir(filepath.Join(i.path, "instances", name), 0755)
+	if err != nil {
+		return nil, i.wrapErr(err)
+	}
+
+	return i.child(name), nil
+}
+
+// GetOrCreateInstance returns the named child instance, creating it if it
+// does not exist. This only works when called on the root instance.
+func (i *Instance) GetOrCreateInstance(name string) (*Instance, error) {
+	child, ok, err := i.Instance(name)
 	if err != nil {
 		return nil, err
+	} else if ok {
+		return child, nil
+	}
+
+	child, err = i.NewInstance(name)
+	if errors.Is(err, os.ErrExist) {
+		// Created concurrently by someone else.
+		return i.child(name), nil
+	}
+	return child, err
+}
+
+// Instance looks up the named child instance without creating it. The bool
+// result reports whether it exists. This only works when called on the root
+// instance.
+func (i *Instance) Instance(name string) (*Instance, bool, error) {
+	if !i.isRoot {
+		return nil, false, fmt.Errorf("Instance must be called on a root instance: %w", ErrNotRoot)
+	}
+
+	if err := validateInstanceName(name); err != nil {
+		return nil, false, err
+	}
+
+	info, err := i.fsys().Stat(filepath.Join(i.path, "instances", name))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, false, nil
+	} else if err != nil {
+		return nil, false, i.wrapErr(err)
+	}
+	if !info.IsDir() {
+		return nil, false, fmt.Errorf("instance %s is not a directory", name)
+	}
+
+	return i.child(name), true, nil
+}
+
+// validateInstanceName checks that name is a single path component made of
+// letters, digits, '_', '-' and '.', so it cannot escape the instances
+// directory.
+func validateInstanceName(name string) error {
+	if name == "" || name == "." || name == ".." {
+		return fmt.Errorf("%w: invalid instance name %q", ErrInvalidValue, name)
+	}
+	for _, c := range name {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '_', c == '-', c == '.':
+		default:
+			return fmt.Errorf("%w: invalid character %q in instance name %q", ErrInvalidValue, c, name)
+		}
+	}
+	return nil
+}
+
+// validateProbeName checks the group and event names of a probe, which are
+// used as directory names under events/. Empty names are allowed since the
+// kernel supplies defaults.
+func validateProbeName(group, event string) error {
+	if group != "" && !validName(group) {
+		return fmt.Errorf("%w: invalid probe group name %q", ErrInvalidValue, group)
 	}
+	if event != "" && !validName(event) {
+		return fmt.Errorf("%w: invalid probe event name %q", ErrInvalidValue, event)
+	}
+	return nil
+}
 
+func (i *Instance) child(name string) *Instance {
 	return &Instance{
-		path: childPath,
+		path: filepath.Join(i.path, "instances", name),
 		name: name,
-	}, nil
+		cfg:  i.cfg,
+		mu:   i.mu,
+	}
+}
+
+// lock acquires the instance's write lock and returns the unlock func.
+func (i *Instance) lock() func() {
+	if i.mu == nil {
+		return func() {}
+	}
+	i.mu.Lock()
+	return i.mu.Unlock
 }
 
-// Destory tracer instance. This does not work on the root instance
+// Destory tracer instance. This does not work on the root instance. The
+// kernel refuses to remove an instance while its files are open (e.g. a
+// trace_pipe reader) or its events are in use by perf; this is reported as
+// an error matching ErrInstanceBusy.
 func (i *Instance) Destroy() error {
 	if i.isRoot {
-		return fmt.Errorf("cannot destroy the root tracer instance")
+		return fmt.Errorf("cannot destroy the root tracer instance: %w", ErrRootInstance)
 	}
 
-	return os.Remove(i.path)
+	err := i.fsys().Remove(i.path)
+	if errors.Is(err, syscall.EBUSY) {
+		return wrapKind(ErrInstanceBusy, fmt.Errorf("instance %s is in use; close open trace files and perf users before destroying it: %w", i.name, err))
+	}
+	return i.wrapErr(err)
 }
 
+// appendLine appends line (plus a trailing newline) to the named file.
+func (i *Instance) appendLine(name, line string) error {
+	return i.wrapErr(i.writePathFlag(filepath.Join(i.path, name), []byte(line+"\n"), os.O_APPEND|os.O_WRONLY))
+}
+
+// AddUprobeEvent validates e and adds it to uprobe_events. Probes are
+// global: on a child instance the probe is defined in the root, and can
+// then be enabled in any instance.
 func (i *Instance) AddUprobeEvent(e *UprobeEvent) error {
-	f, err := os.OpenFile(filepath.Join(i.path, "uprobe_events"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-	if err != nil {
+	if err := e.Validate(); err != nil {
 		return err
 	}
-	defer f.Close()
 
-	_, err = fmt.Fprintln(f, e.Rule())
+	root := i.top()
+	rule := e.Rule()
+	return root.annotateErr(root.appendLine(root.probeEventsFile("uprobe_events"), rule), rule)
+}
+
+// RuleError is a probe rule the kernel or validation rejected.
+type RuleError struct {
+	Rule string
+	// Index is the position of the rule in its batch, or 0 for a single
+	// rule.
+	Index int
+	Err   error
+}
+
+func (e *RuleError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Rule, e.Err)
+}
+
+func (e *RuleError) Unwrap() error {
+	return e.Err
+}
+
+// BatchError is returned by batch operations when some items failed. The
+// other items were applied.
+type BatchError struct {
+	Errors []*RuleError
+	// Total is the number of items in the batch.
+	Total int
+}
+
+func (e *BatchError) Error() string {
+	if len(e.Errors) == 1 {
+		return fmt.Sprintf("1 of %d rules failed: %s", e.Total, e.Errors[0])
+	}
+	return fmt.Sprintf("%d of %d rules failed, first: %s", len(e.Errors), e.Total, e.Errors[0])
+}
+
+// AddUprobeEvents adds events to uprobe_events with a single open of the
+// file. Each rule is written separately, so a rule that fails validation or
+// is rejected by the kernel does not prevent the others from being added;
+// the failures are returned as a *BatchError.
+func (i *Instance) AddUprobeEvents(events []*UprobeEvent) error {
+	root := i.top()
+	defer root.lock()()
+
+	name := filepath.Join(root.path, root.probeEventsFile("uprobe_events"))
+	f, err := openRetry(root.fsys(), name, os.O_APPEND|os.O_WRONLY, root.writeMode())
 	if err != nil {
-		return err
+		return root.wrapErr(err)
+	}
+
+	batchErr := &BatchError{Total: len(events)}
+	for n, e := range events {
+		if err := e.Validate(); err != nil {
+			batchErr.Errors = append(batchErr.Errors, &RuleError{Rule: e.Rule(), Index: n, Err: err})
+			continue
+		}
+		rule := e.Rule()
+		if err := writeAll(f, []byte(rule+"\n")); err != nil {
+			err = root.annotateErr(root.wrapErr(err), rule)
+			batchErr.Errors = append(batchErr.Errors, &RuleError{Rule: rule, Index: n, Err: err})
+		}
 	}
 
-	return f.Close()
+	if err := f.Close(); err != nil {
+		return root.wrapErr(err)
+	}
+	if len(batchErr.Errors) > 0 {
+		return batchErr
+	}
+	return nil
 }
 
+// RemoveUprobeEvent disables and then deletes e from uprobe_events. If e has
+// no Event name, the kernel generated name is looked up by Path and Offset.
+// On a child instance the probe is disabled in the child and then removed
+// from the root.
 func (i *Instance) RemoveUprobeEvent(e *UprobeEvent) error {
-	f, err := os.OpenFile(filepath.Join(i.path, "uprobe_events"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-	if err != nil {
+	if e.Event == "" {
+		found, err := i.findUprobeEvent(e.Path, e.Offset)
+		if err != nil {
+			return err
+		}
+		e = found
+	}
+
+	err := i.DisableUprobe(e)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
 		return err
 	}
-	defer f.Close()
 
-	_, err = fmt.Fprintln(f, e.RemoveRule())
+	root := i.top()
+	err = root.appendLine(root.probeEventsFile("uprobe_events"), e.RemoveRule())
+	if errors.Is(err, syscall.EBUSY) {
+		return wrapKind(ErrProbeBusy, fmt.Errorf("uprobe %s is busy (still enabled or in use by perf): %w", e.Name(), err))
+	}
+	return err
+}
+
+// ClearUprobeEvents removes all uprobe events by truncating uprobe_events.
+func (i *Instance) ClearUprobeEvents() error {
+	return i.top().replaceFile("uprobe_events", nil)
+}
+
+// findUprobeEvent looks up the registered uprobe for path and offset. This is
+// used to find the kernel generated name for events added without one.
+func (i *Instance) findUprobeEvent(path string, offset uint64) (*UprobeEvent, error) {
+	events, err := i.UprobeEvents()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return f.Close()
+	for _, e := range events {
+		if e.Path == path && e.Offset == offset {
+			return e, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no uprobe event found for %s:0x%x", path, offset)
+}
+
+// UprobeEvents returns the registered uprobes parsed from uprobe_events.
+func (i *Instance) UprobeEvents() ([]*UprobeEvent, error) {
+	lines, err := i.top().readLines("uprobe_events")
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]*UprobeEvent, 0, len(lines))
+	for _, line := range lines {
+		e, err := ParseUprobeRule(line)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, e)
+	}
+	return out, nil
 }
 
+// ParseUprobeRule parses a uprobe definition as produced by Rule or listed
+// in uprobe_events, e.g. "p:uprobes/p_bash_0x4245c0 /bin/bash:0x4245c0 arg1=%di".
+func ParseUprobeRule(rule string) (*UprobeEvent, error) {
+	fields := strings.Fields(rule)
+	if len(fields) < 2 || len(fields[0]) == 0 {
+		return nil, fmt.Errorf("invalid uprobe rule %q", rule)
+	}
+
+	var e UprobeEvent
+	switch fields[0][0] {
+	case 'p':
+	case 'r':
+		e.ReturnProbe = true
+	default:
+		return nil, fmt.Errorf("invalid uprobe type in %q", rule)
+	}
+
+	if _, name, ok := strings.Cut(fields[0], ":"); ok {
+		if group, event, ok := strings.Cut(name, "/"); ok {
+			e.Group, e.Event = group, event
+		} else {
+			e.Event = name
+		}
+	}
+
+	target := fields[1]
+	// Strip an optional reference counter offset, e.g. "/bin/foo:0x10(0x20)".
+	if idx := strings.Index(target, "("); idx >= 0 {
+		target = target[:idx]
+	}
+	idx := strings.LastIndex(target, ":")
+	if idx < 0 {
+		return nil, fmt.Errorf("invalid uprobe target in %q", rule)
+	}
+	off, err := strconv.ParseUint(target[idx+1:], 0, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid uprobe offset in %q: %w", rule, err)
+	}
+	e.Path = target[:idx]
+	e.Offset = off
+
+	for _, arg := range fields[2:] {
+		e.FetchArgs = append(e.FetchArgs, parseFetchArg(arg))
+	}
+
+	return &e, nil
+}
+
+// TracePipe opens trace_pipe, a consuming reader of the trace buffer.
+// Reads block until events are available; see TracePipeNonblock to drain
+// the buffer without waiting.
 func (i *Instance) TracePipe() (io.ReadCloser, error) {
-	return os.Open(filepath.Join(i.path, "trace_pipe"))
+	return i.open("trace_pipe")
 }
 
+// Trace returns the current contents of the trace buffer. Unlike
+// TracePipe, reading trace does not consume the events.
+func (i *Instance) Trace() ([]byte, error) {
+	data, err := readPath(i.fsys(), filepath.Join(i.path, "trace"))
+	return data, i.wrapErr(err)
+}
+
+// ClearTrace empties the trace buffer. It does not change tracing_on.
+func (i *Instance) ClearTrace() error {
+	return i.replaceFile("trace", nil)
+}
+
+// TraceReader opens the trace file for streaming a static snapshot of the
+// buffer. Reading does not consume events and does not block waiting for
+// new ones.
+func (i *Instance) TraceReader() (io.ReadCloser, error) {
+	return i.open("trace")
+}
+
+// UprobeEvent is a user space probe for uprobe_events. Unlike KprobeEvent
+// it has no MaxActive: uretprobes record return addresses per task rather
+// than from a fixed pool of instances, so the kernel has no maxactive
+// setting for them.
 type UprobeEvent struct {
 	ReturnProbe bool
 	Group       string
@@ -203,6 +732,48 @@ type UprobeEvent struct {
 	Path        string
 	Offset      uint64
 	FetchArgs   []FetchArg
+	// AllowZeroOffset permits an Offset of 0, which Validate otherwise
+	// rejects as almost certainly a mistake.
+	AllowZeroOffset bool
+}
+
+// Validate checks e for mistakes the kernel would otherwise reject with a
+// generic error: Path must be a readable regular file, Offset must be
+// non-zero unless AllowZeroOffset is set, and the group, event, and fetch
+// argument names must be valid.
+func (e *UprobeEvent) Validate() error {
+	if e.Path == "" {
+		return fmt.Errorf("%w: uprobe path must not be empty", ErrInvalidValue)
+	}
+	f, err := os.Open(e.Path)
+	if err != nil {
+		return fmt.Errorf("%w: uprobe path: %v", ErrInvalidValue, err)
+	}
+	info, err := f.Stat()
+	f.Close()
+	if err != nil {
+		return fmt.Errorf("%w: uprobe path: %v", ErrInvalidValue, err)
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("%w: uprobe path %s is not a regular file", ErrInvalidValue, e.Path)
+	}
+	if e.Offset == 0 && !e.AllowZeroOffset {
+		return fmt.Errorf("%w: uprobe offset is 0 (set AllowZeroOffset if intended)", ErrInvalidValue)
+	}
+	if e.Offset >= uint64(info.Size()) {
+		return fmt.Errorf("%w: uprobe offset 0x%x is beyond the end of %s (%d bytes)", ErrInvalidValue, e.Offset, e.Path, info.Size())
+	}
+
+	if err := validateProbeName(e.Group, e.Event); err != nil {
+		return err
+	}
+	if err := validateFetchArgs(e.FetchArgs); err != nil {
+		return err
+	}
+	if !e.ReturnProbe && usesRetval(e.FetchArgs) {
+		return fmt.Errorf("%w: $retval can only be used on a return uprobe", ErrInvalidValue)
+	}
+	return nil
 }
 
 func (e *UprobeEvent) Rule() string {
@@ -229,52 +800,50 @@ func (e *UprobeEvent) Rule() string {
 	return builder.String()
 }
 
+// RemoveRule returns the uprobe_events command that deletes e. The kernel
+// requires an event name to delete a probe. The group is always included,
+// since dynamic_events deletes every event matching a bare name, including
+// kprobes and synthetic events.
 func (e *UprobeEvent) RemoveRule() string {
-	var builder strings.Builder
-	builder.Write([]byte("-"))
-	if e.Group != "" && e.Event != "" {
-		fmt.Fprintf(&builder, ":%s/%s", e.Group, e.Event)
-	} else if e.Event != "" {
-		fmt.Fprintf(&builder, ":%s", e.Event)
+	group := e.Group
+	if group == "" {
+		group = defaultUprobeGroup
 	}
+	return "-:" + group + "/" + e.Event
+}
 
-	fmt.Fprintf(&builder, " %s:0x%016x", e.Path, e.Offset)
+// defaultUprobeGroup is the group the kernel puts uprobes in when the rule
+// does not name one.
+const defaultUprobeGroup = "uprobes"
 
-	for _, arg := range e.FetchArgs {
-		fmt.Fprintf(&builder, " %s", arg.String())
+// Name returns the probe name in [group/]event form.
+func (e *UprobeEvent) Name() string {
+	if e.Group != "" && e.Event != "" {
+		return e.Group + "/" + e.Event
 	}
-
-	return builder.String()
-
+	return e.Event
 }
 
 func (i *Instance) UprobeEnablePath(e *UprobeEvent) string {
 	if e.Group != "" && e.Event != "" {
 		return filepath.Join(i.path, "events", e.Group, e.Event, "enable")
 	} else if e.Event != "" {
-		return filepath.Join(i.path, "events", "uprobes", e.Event, "enable")
+		return filepath.Join(i.path, "events", defaultUprobeGroup, e.Event, "enable")
 	}
 
-	return filepath.Join(i.path, "events", "uprobes", "enable")
+	return filepath.Join(i.path, "events", defaultUprobeGroup, "enable")
 }
 
 func (i *Instance) EnableUprobe(e *UprobeEvent) error {
-	return ioutil.WriteFile(i.UprobeEnablePath(e), []byte("1"), 0777)
+	if err := validateProbeName(e.Group, e.Event); err != nil {
+		return err
+	}
+	return i.wrapErr(i.writePath(i.UprobeEnablePath(e), []byte("1"), false))
 }
 
 func (i *Instance) DisableUprobe(e *UprobeEvent) error {
-	return ioutil.WriteFile(i.UprobeEnablePath(e), []byte("0"), 0777)
-}
-
-type FetchArg interface {
-	Type() string
-	String() string
-}
-
-type fetchRegister struct {
-	register string
-}
-
-func (f fetchRegister) String() string {
-	return f.register
+	if err := validateProbeName(e.Group, e.Event); err != nil {
+		return err
+	}
+	return i.wrapErr(i.writePath(i.UprobeEnablePath(e), []byte("0"), false))
 }
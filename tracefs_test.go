@@ -0,0 +1,326 @@
+package tracefs
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"syscall"
+	"testing"
+)
+
+func TestParseUprobeRule(t *testing.T) {
+	tests := []struct {
+		rule string
+		want UprobeEvent
+	}{
+		{
+			rule: "p:uprobes/readline /bin/bash:0x00000000000b8a40",
+			want: UprobeEvent{Group: "uprobes", Event: "readline", Path: "/bin/bash", Offset: 0xb8a40},
+		},
+		{
+			rule: "r:bash/readline_ret /bin/bash:0x00000000000b8a40 line=+0($retval):string",
+			want: UprobeEvent{
+				ReturnProbe: true,
+				Group:       "bash",
+				Event:       "readline_ret",
+				Path:        "/bin/bash",
+				Offset:      0xb8a40,
+				FetchArgs:   []FetchArg{Named("line", WithType(FetchMemory(0, FetchRetval()), ArgString))},
+			},
+		},
+		{
+			rule: "p /usr/lib/libc.so.6:0x0000000000001000 %di %si:x64",
+			want: UprobeEvent{
+				Path:      "/usr/lib/libc.so.6",
+				Offset:    0x1000,
+				FetchArgs: []FetchArg{FetchRegister("di"), WithType(FetchRegister("si"), ArgX64)},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseUprobeRule(tt.rule)
+		if err != nil {
+			t.Errorf("ParseUprobeRule(%q): %v", tt.rule, err)
+			continue
+		}
+		if got.Rule() != tt.rule {
+			t.Errorf("ParseUprobeRule(%q).Rule() = %q", tt.rule, got.Rule())
+		}
+		if got.Group != tt.want.Group || got.Event != tt.want.Event || got.Path != tt.want.Path ||
+			got.Offset != tt.want.Offset || got.ReturnProbe != tt.want.ReturnProbe {
+			t.Errorf("ParseUprobeRule(%q) = %+v, want %+v", tt.rule, got, tt.want)
+		}
+		if len(got.FetchArgs) != len(tt.want.FetchArgs) {
+			t.Errorf("ParseUprobeRule(%q) has %d fetch args, want %d", tt.rule, len(got.FetchArgs), len(tt.want.FetchArgs))
+			continue
+		}
+		for n := range got.FetchArgs {
+			if got.FetchArgs[n] != tt.want.FetchArgs[n] {
+				t.Errorf("ParseUprobeRule(%q) arg %d = %v, want %v", tt.rule, n, got.FetchArgs[n], tt.want.FetchArgs[n])
+			}
+		}
+	}
+}
+
+func TestParseUprobeRuleRefCounter(t *testing.T) {
+	e, err := ParseUprobeRule("p:sdt/probe /bin/app:0x10(0x20)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Path != "/bin/app" || e.Offset != 0x10 {
+		t.Errorf("got path %q offset %#x, want /bin/app 0x10", e.Path, e.Offset)
+	}
+}
+
+func TestParseUprobeRuleInvalid(t *testing.T) {
+	for _, rule := range []string{
+		"",
+		"p",
+		"x:uprobes/foo /bin/bash:0x10",
+		"p:uprobes/foo /bin/bash",
+		"p:uprobes/foo /bin/bash:zz",
+	} {
+		if _, err := ParseUprobeRule(rule); err == nil {
+			t.Errorf("ParseUprobeRule(%q) succeeded", rule)
+		}
+	}
+}
+
+func TestRemoveRule(t *testing.T) {
+	tests := []struct {
+		e    UprobeEvent
+		want string
+	}{
+		{UprobeEvent{Group: "bash", Event: "readline"}, "-:bash/readline"},
+		{UprobeEvent{Event: "readline"}, "-:uprobes/readline"},
+	}
+	for _, tt := range tests {
+		if got := tt.e.RemoveRule(); got != tt.want {
+			t.Errorf("RemoveRule() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+// newTestChild returns a root instance over fsys at /t and its child
+// "child". Both have the per-instance control files; only the root has
+// tracing_thresh and uprobe_events.
+func newTestChild(t *testing.T) (*memFS, Instance, *Instance) {
+	t.Helper()
+	fsys := newTracefs("/t", map[string]string{
+		"/t/tracing_thresh":                 "0\n",
+		"/t/uprobe_events":                  "",
+		"/t/buffer_size_kb":                 "1408\n",
+		"/t/instances/child/trace":          "",
+		"/t/instances/child/tracing_on":     "1\n",
+		"/t/instances/child/current_tracer": "nop\n",
+		"/t/instances/child/buffer_size_kb": "7\n",
+	})
+	root := RootInstance("/t", WithFS(fsys))
+	child, ok, err := root.Instance("child")
+	if err != nil || !ok {
+		t.Fatalf("Instance(child) = %v, %v", ok, err)
+	}
+	return fsys, root, child
+}
+
+func TestTop(t *testing.T) {
+	_, root, child := newTestChild(t)
+
+	if top := root.top(); top != &root {
+		t.Errorf("root.top() = %p, want the root itself %p", top, &root)
+	}
+
+	top := child.top()
+	if !top.IsRoot() || top.Path() != "/t" {
+		t.Errorf("child.top() = root %v at %q, want root at /t", top.IsRoot(), top.Path())
+	}
+	if top.mu != root.mu || child.mu != root.mu {
+		t.Error("child and its root do not share the root's lock")
+	}
+	if top.fsys() != root.fsys() {
+		t.Error("child.top() does not use the root's FS")
+	}
+}
+
+func TestChildRootOnlyFile(t *testing.T) {
+	fsys, _, child := newTestChild(t)
+
+	_, err := child.readFile("tracing_thresh")
+	if !errors.Is(err, ErrNotRoot) {
+		t.Errorf("reading a root only file from a child = %v, want ErrNotRoot", err)
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("ErrNotRoot error %v does not match os.ErrNotExist", err)
+	}
+
+	_, err = child.readFile("no_such_file")
+	if errors.Is(err, ErrNotRoot) || !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("reading a missing file from a child = %v, want only os.ErrNotExist", err)
+	}
+
+	if err := fsys.Remove("/t/instances/child"); err != nil {
+		t.Fatal(err)
+	}
+	_, err = child.readFile("tracing_thresh")
+	if errors.Is(err, ErrNotRoot) {
+		t.Errorf("reading from a destroyed child = %v, want no ErrNotRoot", err)
+	}
+}
+
+func TestRootNotMounted(t *testing.T) {
+	// The mount point exists, but tracing_on does not.
+	fsys := newMemFS(nil)
+	fsys.dirs["/t"] = true
+	root := RootInstance("/t", WithFS(fsys))
+
+	if _, err := root.readFile("current_tracer"); !errors.Is(err, ErrNotMounted) {
+		t.Errorf("readFile on an empty mount point = %v, want ErrNotMounted", err)
+	}
+}
+
+func TestChildAddUprobeEvent(t *testing.T) {
+	fsys, _, child := newTestChild(t)
+
+	bin := filepath.Join(t.TempDir(), "bin")
+	if err := os.WriteFile(bin, make([]byte, 4096), 0644); err != nil {
+		t.Fatal(err)
+	}
+	e := &UprobeEvent{Group: "test", Event: "probe", Path: bin, Offset: 0x100}
+
+	if err := child.AddUprobeEvent(e); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := fsys.content("/t/uprobe_events"), e.Rule()+"\n"; got != want {
+		t.Errorf("root uprobe_events = %q, want %q", got, want)
+	}
+	if fsys.exists("/t/instances/child/uprobe_events") {
+		t.Error("AddUprobeEvent created uprobe_events in the child")
+	}
+
+	if err := child.AddUprobeEvents([]*UprobeEvent{e}); err != nil {
+		t.Fatal(err)
+	}
+	if got := fsys.written("/t/uprobe_events"); len(got) != 2 {
+		t.Errorf("root uprobe_events writes = %q, want 2", got)
+	}
+}
+
+func TestChildWritesOwnFiles(t *testing.T) {
+	fsys, _, child := newTestChild(t)
+
+	if err := child.SetTracer(FunctionTracer); err != nil {
+		t.Fatal(err)
+	}
+	if err := child.Disable(); err != nil {
+		t.Fatal(err)
+	}
+	if err := child.Enable(); err != nil {
+		t.Fatal(err)
+	}
+	if err := child.SetBufferSizeKB(1024); err != nil {
+		t.Fatal(err)
+	}
+	if kb, err := child.BufferSizeKB(); err != nil || kb != 1024 {
+		t.Errorf("child BufferSizeKB = %d, %v, want 1024", kb, err)
+	}
+
+	for file, want := range map[string][]string{
+		"current_tracer": {"function"},
+		"tracing_on":     {"0", "1"},
+		"buffer_size_kb": {"1024"},
+	} {
+		if got := fsys.written("/t/instances/child/" + file); !reflect.DeepEqual(got, want) {
+			t.Errorf("child %s writes = %q, want %q", file, got, want)
+		}
+		if got := fsys.written("/t/" + file); len(got) != 0 {
+			t.Errorf("root %s was written %q by child methods", file, got)
+		}
+	}
+	if got := fsys.content("/t/buffer_size_kb"); got != "1408\n" {
+		t.Errorf("root buffer_size_kb = %q, want it unchanged", got)
+	}
+}
+
+func TestChildOpenRootOnlyFile(t *testing.T) {
+	_, _, child := newTestChild(t)
+
+	if _, err := child.Open("tracing_thresh"); !errors.Is(err, ErrNotRoot) {
+		t.Errorf("child Open(tracing_thresh) = %v, want ErrNotRoot", err)
+	}
+	if _, err := child.Open("trace"); err != nil {
+		t.Errorf("child Open(trace) = %v", err)
+	}
+}
+
+func TestChildGlobalFilesUseRoot(t *testing.T) {
+	fsys, _, child := newTestChild(t)
+	fsys.addFile("/t/uprobe_events", "p:uprobes/old /bin/sh:0x0000000000000010\n")
+
+	events, err := child.UprobeEvents()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].Event != "old" {
+		t.Errorf("child UprobeEvents = %v, want the root's probe", events)
+	}
+
+	if err := child.ClearUprobeEvents(); err != nil {
+		t.Fatal(err)
+	}
+	if got := fsys.content("/t/uprobe_events"); got != "" {
+		t.Errorf("root uprobe_events = %q after ClearUprobeEvents on a child", got)
+	}
+}
+
+func TestOpenPathValidation(t *testing.T) {
+	root := RootInstance("/t", WithFS(newTracefs("/t", nil)))
+
+	for _, name := range []string{"", "/etc/passwd", "..", "../x", "options/../../x"} {
+		if _, err := root.Open(name); !errors.Is(err, ErrInvalidValue) {
+			t.Errorf("Open(%q) = %v, want ErrInvalidValue", name, err)
+		}
+	}
+	if _, err := root.Open("options/../current_tracer"); err != nil {
+		t.Errorf("Open of a path that stays in the instance: %v", err)
+	}
+}
+
+func TestAddUprobeEventsPartialFailure(t *testing.T) {
+	fsys := newTracefs("/t", map[string]string{"/t/uprobe_events": ""})
+	// The kernel rejects the second rule.
+	fsys.writeErrs["/t/uprobe_events"] = []error{nil, syscall.EINVAL}
+	root := RootInstance("/t", WithFS(fsys))
+
+	bin := filepath.Join(t.TempDir(), "bin")
+	if err := os.WriteFile(bin, make([]byte, 4096), 0644); err != nil {
+		t.Fatal(err)
+	}
+	events := []*UprobeEvent{
+		{Group: "test", Event: "first", Path: bin, Offset: 0x100},
+		{Group: "test", Event: "second", Path: bin, Offset: 0x200},
+		{Group: "test", Event: "third", Path: bin, Offset: 0x300},
+	}
+
+	err := root.AddUprobeEvents(events)
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) {
+		t.Fatalf("AddUprobeEvents = %v, want a *BatchError", err)
+	}
+	if batchErr.Total != 3 || len(batchErr.Errors) != 1 {
+		t.Fatalf("BatchError = %v, want 1 of 3 rules failed", batchErr)
+	}
+	ruleErr := batchErr.Errors[0]
+	if ruleErr.Index != 1 || ruleErr.Rule != events[1].Rule() {
+		t.Errorf("failed rule %d %q, want 1 %q", ruleErr.Index, ruleErr.Rule, events[1].Rule())
+	}
+	if !errors.Is(ruleErr, ErrInvalidValue) || !errors.Is(ruleErr, syscall.EINVAL) {
+		t.Errorf("RuleError %v does not match ErrInvalidValue and EINVAL", ruleErr)
+	}
+
+	want := events[0].Rule() + "\n" + events[2].Rule() + "\n"
+	if got := fsys.content("/t/uprobe_events"); got != want {
+		t.Errorf("uprobe_events = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,179 @@
+package tracefs
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TraceEvent is a single parsed line of trace or trace_pipe output.
+type TraceEvent struct {
+	Comm string
+	PID  int
+	// TGID is set when the record-tgid option is on, and is 0 otherwise or
+	// when the kernel does not know the tgid.
+	TGID  int
+	CPU   int
+	Flags string
+	// Timestamp is the event time. In latency format it is relative to the
+	// start of the trace.
+	Timestamp time.Duration
+	// Function is the event name (e.g. sched_switch) or, for the function
+	// tracers, the traced function.
+	Function string
+	Rest     string
+}
+
+var (
+	// comm-pid (  tgid) [cpu] flags timestamp: rest
+	traceLineRe = regexp.MustCompile(`^\s*(.*)-(\d+)\s+(?:\(\s*([-\d]+)\)\s+)?\[(\d+)\]\s+(?:(\S{4,5})\s+)?(\d+(?:\.\d+)?):\s(.*)$`)
+	// comm-pid cpu+flags timestamp-us mark: rest
+	latencyLineRe = regexp.MustCompile(`^\s*(.*)-(\d+)\s+(\d+)(\D\S{3,4})\s+(\d+)us(\S?)\s?:\s(.*)$`)
+)
+
+// ParseTraceLine parses a line of trace output in either the default or
+// the latency format.
+func ParseTraceLine(line string) (TraceEvent, error) {
+	var ev TraceEvent
+
+	if m := traceLineRe.FindStringSubmatch(line); m != nil {
+		ev.Comm = strings.TrimSpace(m[1])
+		ev.PID, _ = strconv.Atoi(m[2])
+		ev.TGID, _ = strconv.Atoi(m[3])
+		ev.CPU, _ = strconv.Atoi(m[4])
+		ev.Flags = m[5]
+		ts, err := parseTraceTimestamp(m[6])
+		if err != nil {
+			return ev, err
+		}
+		ev.Timestamp = ts
+		ev.Function, ev.Rest = splitTraceFunction(m[7])
+		return ev, nil
+	}
+
+	if m := latencyLineRe.FindStringSubmatch(line); m != nil {
+		ev.Comm = strings.TrimSpace(m[1])
+		ev.PID, _ = strconv.Atoi(m[2])
+		ev.CPU, _ = strconv.Atoi(m[3])
+		ev.Flags = m[4]
+		us, _ := strconv.ParseInt(m[5], 10, 64)
+		ev.Timestamp = time.Duration(us) * time.Microsecond
+		ev.Function, ev.Rest = splitTraceFunction(m[7])
+		return ev, nil
+	}
+
+	return ev, fmt.Errorf("unrecognized trace line: %q", line)
+}
+
+// parseTraceTimestamp parses a seconds.microseconds timestamp. Timestamps
+// from counter clocks have no fractional part.
+func parseTraceTimestamp(s string) (time.Duration, error) {
+	secStr, fracStr, _ := strings.Cut(s, ".")
+	sec, err := strconv.ParseInt(secStr, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid timestamp %q: %w", s, err)
+	}
+	if fracStr == "" {
+		return time.Duration(sec), nil
+	}
+
+	if len(fracStr) > 9 {
+		fracStr = fracStr[:9]
+	}
+	frac, err := strconv.ParseInt(fracStr+strings.Repeat("0", 9-len(fracStr)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid timestamp %q: %w", s, err)
+	}
+	return time.Duration(sec)*time.Second + time.Duration(frac), nil
+}
+
+// splitTraceFunction splits "sched_switch: prev_comm=..." or
+// "do_sys_open <-sys_openat" into the event or function name and the rest.
+func splitTraceFunction(s string) (string, string) {
+	name, rest, _ := strings.Cut(strings.TrimSpace(s), " ")
+	return strings.TrimSuffix(name, ":"), strings.TrimSpace(rest)
+}
+
+// TraceScanner reads TraceEvents from trace output. Comment lines and lines
+// that cannot be parsed (such as lost event notices) are skipped.
+type TraceScanner struct {
+	scanner *bufio.Scanner
+	event   TraceEvent
+}
+
+// NewTraceScanner returns a TraceScanner reading from r, typically the
+// reader returned by TracePipe.
+func NewTraceScanner(r io.Reader) *TraceScanner {
+	return &TraceScanner{
+		scanner: newLineScanner(r),
+	}
+}
+
+// Scan advances to the next event. It returns false at the end of input or
+// on error.
+func (s *TraceScanner) Scan() bool {
+	for s.scanner.Scan() {
+		line := s.scanner.Text()
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		ev, err := ParseTraceLine(line)
+		if err != nil {
+			continue
+		}
+		s.event = ev
+		return true
+	}
+	return false
+}
+
+// Event returns the most recent event read by Scan.
+func (s *TraceScanner) Event() TraceEvent {
+	return s.event
+}
+
+// Err returns the first non-EOF error encountered by Scan.
+func (s *TraceScanner) Err() error {
+	return s.scanner.Err()
+}
+
+// maxTraceLineLen is the longest trace line read. bufio.Scanner's default
+// of 64KB is too small for events with large string or array fields.
+const maxTraceLineLen = 16 << 20
+
+// newLineScanner returns a line scanner for trace output that accepts lines
+// up to maxTraceLineLen.
+func newLineScanner(r io.Reader) *bufio.Scanner {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64<<10), maxTraceLineLen)
+	return scanner
+}
+
+// TraceLineReader reads whole lines from trace output such as trace_pipe.
+// Lines are reassembled across reads, so an event split over several reads
+// is returned intact.
+type TraceLineReader struct {
+	scanner *bufio.Scanner
+}
+
+// NewTraceLineReader returns a TraceLineReader reading from r.
+func NewTraceLineReader(r io.Reader) *TraceLineReader {
+	return &TraceLineReader{scanner: newLineScanner(r)}
+}
+
+// ReadLine returns the next line without its trailing newline. It returns
+// io.EOF at the end of input, and bufio.ErrTooLong for a line longer than
+// 16MB.
+func (r *TraceLineReader) ReadLine() (string, error) {
+	if r.scanner.Scan() {
+		return r.scanner.Text(), nil
+	}
+	if err := r.scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", io.EOF
+}
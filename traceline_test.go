@@ -0,0 +1,97 @@
+package tracefs
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseTraceLine(t *testing.T) {
+	tests := []struct {
+		line string
+		want TraceEvent
+	}{
+		{
+			line: "          <idle>-0       [001] d..2. 12345.678901: sched_switch: prev_comm=swapper/1 prev_pid=0",
+			want: TraceEvent{
+				Comm:      "<idle>",
+				PID:       0,
+				CPU:       1,
+				Flags:     "d..2.",
+				Timestamp: 12345*time.Second + 678901*time.Microsecond,
+				Function:  "sched_switch",
+				Rest:      "prev_comm=swapper/1 prev_pid=0",
+			},
+		},
+		{
+			// record-tgid and a comm containing '-' and spaces.
+			line: " kworker/u8:2-my task-4321  (  4300) [003] .... 1.000000500: do_sys_open <-__x64_sys_openat",
+			want: TraceEvent{
+				Comm:      "kworker/u8:2-my task",
+				PID:       4321,
+				TGID:      4300,
+				CPU:       3,
+				Flags:     "....",
+				Timestamp: time.Second + 500,
+				Function:  "do_sys_open",
+				Rest:      "<-__x64_sys_openat",
+			},
+		},
+		{
+			// A counter clock has no fractional part, and the flags
+			// column is disabled.
+			line: "bash-100 [000] 987654: tracing_mark_write: hello",
+			want: TraceEvent{
+				Comm:      "bash",
+				PID:       100,
+				Timestamp: 987654,
+				Function:  "tracing_mark_write",
+				Rest:      "hello",
+			},
+		},
+		{
+			// latency-format.
+			line: "  <idle>-0       1d..1    5us : do_idle+0x8c/0x100",
+			want: TraceEvent{
+				Comm:      "<idle>",
+				CPU:       1,
+				Flags:     "d..1",
+				Timestamp: 5 * time.Microsecond,
+				Function:  "do_idle+0x8c/0x100",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseTraceLine(tt.line)
+		if err != nil {
+			t.Errorf("ParseTraceLine(%q): %v", tt.line, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseTraceLine(%q)\n got %+v\nwant %+v", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestParseTraceLineInvalid(t *testing.T) {
+	for _, line := range []string{"", "# tracer: nop", " => do_sys_open"} {
+		if _, err := ParseTraceLine(line); err == nil {
+			t.Errorf("ParseTraceLine(%q) succeeded", line)
+		}
+	}
+}
+
+func TestTraceLineReaderLongLine(t *testing.T) {
+	long := strings.Repeat("x", 128<<10)
+	r := NewTraceLineReader(strings.NewReader("short\n" + long + "\n"))
+	for _, want := range []string{"short", long} {
+		got, err := r.ReadLine()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("ReadLine() returned %d bytes, want %d", len(got), len(want))
+		}
+	}
+}
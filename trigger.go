@@ -0,0 +1,36 @@
+package tracefs
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// AddTrigger attaches trigger to e, e.g. "stacktrace" or
+// "traceoff if prev_pid == 1".
+func (i *Instance) AddTrigger(e Event, trigger string) error {
+	return i.appendLine(filepath.Join(eventDir(e), "trigger"), trigger)
+}
+
+// RemoveTrigger removes trigger from e.
+func (i *Instance) RemoveTrigger(e Event, trigger string) error {
+	return i.appendLine(filepath.Join(eventDir(e), "trigger"), "!"+trigger)
+}
+
+// Triggers returns the triggers attached to e.
+func (i *Instance) Triggers(e Event) ([]string, error) {
+	lines, err := i.readLines(filepath.Join(eventDir(e), "trigger"))
+	if err != nil {
+		return nil, err
+	}
+
+	var out []string
+	for _, line := range lines {
+		// With no triggers set the file lists the available triggers as
+		// comments.
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		out = append(out, line)
+	}
+	return out, nil
+}